@@ -0,0 +1,56 @@
+// Package actions talks to the GitHub Actions runner through the files and environment variables it provides. Every
+// function that does is a no-op when not running inside GitHub Actions.
+package actions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SetOutput sets a step output that later steps can read with ${{ steps.<id>.outputs.<name> }}.
+func SetOutput(name, value string) error {
+	delimiter, err := newDelimiter()
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(value, delimiter) {
+		return fmt.Errorf("output %s contains the delimiter %s", name, delimiter)
+	}
+
+	return appendToEnvFile("GITHUB_OUTPUT", fmt.Sprintf("%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter))
+}
+
+// AppendStepSummary adds markdown to the summary shown on the workflow run page.
+func AppendStepSummary(markdown string) error {
+	return appendToEnvFile("GITHUB_STEP_SUMMARY", markdown)
+}
+
+func appendToEnvFile(envVar, contents string) error {
+	path := os.Getenv(envVar)
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(contents); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func newDelimiter() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "ghadelimiter_" + hex.EncodeToString(b), nil
+}
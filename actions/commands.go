@@ -0,0 +1,74 @@
+package actions
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// IsRunning reports whether the process is running inside GitHub Actions.
+func IsRunning() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// Error shows an error annotation on the workflow run summary page.
+func Error(title, message string) {
+	issueCommand("error", map[string]string{"title": title}, message)
+}
+
+// Warning shows a warning annotation on the workflow run summary page.
+func Warning(title, message string) {
+	issueCommand("warning", map[string]string{"title": title}, message)
+}
+
+// Group starts a collapsible group in the log. Everything logged until EndGroup is called is hidden inside it.
+func Group(name string) {
+	issueCommand("group", nil, name)
+}
+
+func EndGroup() {
+	issueCommand("endgroup", nil, "")
+}
+
+// AddMask stops a value from being shown in the log from now on.
+func AddMask(value string) {
+	if value == "" {
+		return
+	}
+	issueCommand("add-mask", nil, value)
+}
+
+func issueCommand(command string, properties map[string]string, message string) {
+	if !IsRunning() {
+		return
+	}
+
+	var props []string
+	for _, key := range sortedKeys(properties) {
+		props = append(props, fmt.Sprintf("%s=%s", key, escapeProperty(properties[key])))
+	}
+
+	cmd := "::" + command
+	if len(props) > 0 {
+		cmd += " " + strings.Join(props, ",")
+	}
+	fmt.Fprintf(os.Stdout, "%s::%s\n", cmd, escapeData(message))
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
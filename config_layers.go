@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tamj0rd2/pipeline-status-action/configfile"
+	"github.com/tamj0rd2/pipeline-status-action/github"
+)
+
+// envPrefix is prepended to the upper snake case name of a flag to get the environment variable that sets it, e.g
+// PIPELINE_STATUS_TIMEOUT_MINUTES sets timeoutMinutes.
+const envPrefix = "PIPELINE_STATUS_"
+
+// applyConfigLayers fills in every flag that wasn't given from its environment variable, or failing that from the
+// config file. That way flags win over the environment, which wins over the file, which wins over the defaults.
+func applyConfigLayers(flags *flag.FlagSet, file configfile.File) error {
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	fileValues := fileFlagValues(file)
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || given[f.Name] || f.Name == configFlag {
+			return
+		}
+
+		if value := os.Getenv(flagEnvName(f.Name)); value != "" {
+			if setErr := f.Value.Set(value); setErr != nil {
+				err = usageErrorf("$%s is invalid - %w", flagEnvName(f.Name), setErr)
+			}
+			return
+		}
+
+		if value, ok := fileValues[f.Name]; ok {
+			if setErr := f.Value.Set(value); setErr != nil {
+				err = usageErrorf("the config file's value for %s is invalid - %w", f.Name, setErr)
+			}
+		}
+	})
+	return err
+}
+
+// fileFlagValues returns the settings in the config file that have a flag, in the format the flag takes.
+func fileFlagValues(file configfile.File) map[string]string {
+	values := make(map[string]string)
+
+	if checkNames := file.CheckNames(); len(checkNames) > 0 {
+		values["checkNames"] = strings.Join(checkNames, ",")
+	}
+
+	var retries []string
+	for _, pattern := range file.Patterns {
+		if pattern.Retries != nil {
+			retries = append(retries, pattern.Match+"="+strconv.Itoa(*pattern.Retries))
+		}
+	}
+	if len(retries) > 0 {
+		values["retries"] = strings.Join(retries, ",")
+	}
+
+	if checkTimeouts := fileCheckTimeouts(file); len(checkTimeouts) > 0 {
+		values["checkTimeouts"] = formatCheckTimeouts(checkTimeouts)
+	}
+
+	if severities := fileSeverities(file); len(severities) > 0 {
+		values["severities"] = strings.Join(severities, ",")
+	}
+
+	if file.Timeouts.WaitMinutes > 0 {
+		values["timeoutMinutes"] = strconv.Itoa(file.Timeouts.WaitMinutes)
+	}
+
+	if stages := fileStages(file); stages != "" {
+		values["stages"] = stages
+	}
+
+	if file.Policy != "" {
+		values["policy"] = file.Policy
+	}
+
+	if file.Mode != "" {
+		values["mode"] = file.Mode
+	}
+
+	if file.EarlyAlerts != nil {
+		values["earlyAlerts"] = strconv.FormatBool(*file.EarlyAlerts)
+	}
+
+	if notifier, ok := file.Notifiers[configfile.DefaultNotifier]; ok {
+		values["slackWebhookURL"] = notifier.URL()
+	}
+
+	return values
+}
+
+// fileCheckTimeouts returns the timeouts of individual checks, followed by those of patterns, so that the most
+// specific ones match first.
+func fileCheckTimeouts(file configfile.File) []github.CheckTimeouts {
+	var timeouts []github.CheckTimeouts
+	for _, check := range file.Checks {
+		if !check.Timeouts.IsZero() {
+			timeouts = append(timeouts, checkTimeouts(escapePattern(check.Name), check.Timeouts))
+		}
+	}
+	for _, pattern := range file.Patterns {
+		if !pattern.Timeouts.IsZero() {
+			timeouts = append(timeouts, checkTimeouts(pattern.Match, pattern.Timeouts))
+		}
+	}
+	return timeouts
+}
+
+// fileSeverities returns the severities of individual checks, followed by those of patterns, in the format the
+// severities flag takes.
+func fileSeverities(file configfile.File) []string {
+	var severities []string
+	for _, check := range file.Checks {
+		if check.Severity != "" {
+			severities = append(severities, escapePattern(check.Name)+"="+check.Severity)
+		}
+	}
+	for _, pattern := range file.Patterns {
+		if pattern.Severity != "" {
+			severities = append(severities, pattern.Match+"="+pattern.Severity)
+		}
+	}
+	return severities
+}
+
+// fileStages returns the stages in the config file in the format the stages flag takes.
+func fileStages(file configfile.File) string {
+	var stages []string
+	for _, stage := range file.Stages {
+		stages = append(stages, stage.Name+"="+strings.Join(stage.Checks, ","))
+	}
+	return strings.Join(stages, ";")
+}
+
+func checkTimeouts(pattern string, t configfile.CheckTimeouts) github.CheckTimeouts {
+	return github.CheckTimeouts{Pattern: pattern, AppearWithin: t.AppearWithin, FinishWithin: t.FinishWithin, ExpectedDuration: t.ExpectedDuration}
+}
+
+// escapePattern makes a check name into a pattern that only matches that name.
+func escapePattern(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// flagEnvName turns a flag name like slackWebhookURL into PIPELINE_STATUS_SLACK_WEBHOOK_URL.
+func flagEnvName(name string) string {
+	var b strings.Builder
+	b.WriteString(envPrefix)
+
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// withoutEmptyFlags drops flags given an empty value, like -checkNames=, because the action passes every input
+// whether or not it was set and an empty one shouldn't hide the value from the environment or config file.
+func withoutEmptyFlags(args []string) []string {
+	var kept []string
+	for i, arg := range args {
+		if arg == "--" {
+			return append(kept, args[i:]...)
+		}
+
+		if strings.HasPrefix(arg, "-") && strings.HasSuffix(arg, "=") && !strings.Contains(strings.TrimSuffix(arg, "="), "=") {
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return kept
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/configfile"
+)
+
+func TestApplyConfigLayers(t *testing.T) {
+	flags := newFlagSet("test")
+	checkNames := flags.String("checkNames", "", "")
+	timeoutMinutes := flags.Int("timeoutMinutes", 30, "")
+	mode := flags.String("mode", "fail-fast", "")
+	policy := flags.String("policy", "", "")
+	retries := flags.String("retries", "", "")
+	flags.String(configFlag, "", "")
+
+	t.Setenv("PIPELINE_STATUS_TIMEOUT_MINUTES", "45")
+	t.Setenv("PIPELINE_STATUS_MODE", "collect-all")
+
+	if err := parseFlags(flags, []string{"-mode=fail-fast", "-policy=", "-config=central.yml"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file := configfile.File{
+		Version:  1,
+		Checks:   []configfile.Check{{Name: "build"}, {Name: "test"}},
+		Timeouts: configfile.Timeouts{WaitMinutes: 60},
+		Policy:   "build AND test",
+		Mode:     "collect-all",
+	}
+	if err := applyConfigLayers(flags, file); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if *checkNames != "build,test" {
+		t.Errorf("expected checkNames from the file, got %q", *checkNames)
+	}
+	if *timeoutMinutes != 45 {
+		t.Errorf("expected timeoutMinutes from the environment over the file, got %d", *timeoutMinutes)
+	}
+	if *mode != "fail-fast" {
+		t.Errorf("expected mode from the flag over the environment and the file, got %q", *mode)
+	}
+	if *policy != "build AND test" {
+		t.Errorf("expected an empty policy flag to be treated as not given, got %q", *policy)
+	}
+	if *retries != "" {
+		t.Errorf("expected retries to keep its default, got %q", *retries)
+	}
+}
+
+func TestApplyConfigLayersInvalidValues(t *testing.T) {
+	flags := newFlagSet("test")
+	flags.Int("timeoutMinutes", 30, "")
+
+	t.Setenv("PIPELINE_STATUS_TIMEOUT_MINUTES", "soon")
+	err := applyConfigLayers(flags, configfile.File{})
+
+	var usageErr usageError
+	if !errors.As(err, &usageErr) {
+		t.Fatalf("expected a usage error, got %v", err)
+	}
+}
+
+func TestFileFlagValues(t *testing.T) {
+	t.Setenv("TEST_SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/test")
+	retries, earlyAlerts := 2, true
+	file := configfile.File{
+		Version: 1,
+		Checks: []configfile.Check{
+			{Name: "build", Timeouts: configfile.CheckTimeouts{AppearWithin: 2 * time.Minute}},
+			{Name: "e2e[chrome]", Severity: "warn"},
+		},
+		Patterns: []configfile.Pattern{
+			{Match: "e2e-*", Retries: &retries, Timeouts: configfile.CheckTimeouts{FinishWithin: 50 * time.Minute}, Severity: "info"},
+		},
+		Timeouts: configfile.Timeouts{WaitMinutes: 45},
+		Stages: []configfile.Stage{
+			{Name: "build", Checks: []string{"build"}},
+			{Name: "test", Checks: []string{"e2e-*", "e2e[chrome]"}},
+		},
+		Policy:      "build",
+		Mode:        "collect-all",
+		EarlyAlerts: &earlyAlerts,
+		Notifiers:   map[string]configfile.Notifier{configfile.DefaultNotifier: {WebhookURLEnv: "TEST_SLACK_WEBHOOK_URL"}},
+	}
+
+	want := map[string]string{
+		"checkNames":      "build,e2e[chrome]",
+		"retries":         "e2e-*=2",
+		"checkTimeouts":   "build=appear:2m0s,e2e-*=finish:50m0s",
+		"severities":      `e2e\[chrome]=warn,e2e-*=info`,
+		"timeoutMinutes":  "45",
+		"stages":          "build=build;test=e2e-*,e2e[chrome]",
+		"policy":          "build",
+		"mode":            "collect-all",
+		"earlyAlerts":     "true",
+		"slackWebhookURL": "https://hooks.slack.com/services/test",
+	}
+	if got := fileFlagValues(file); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if got := fileFlagValues(configfile.File{}); len(got) != 0 {
+		t.Errorf("expected an empty file to set nothing, got %v", got)
+	}
+}
+
+func TestFlagEnvName(t *testing.T) {
+	tests := map[string]string{
+		"checkNames":      "PIPELINE_STATUS_CHECK_NAMES",
+		"slackWebhookURL": "PIPELINE_STATUS_SLACK_WEBHOOK_URL",
+		"timeoutMinutes":  "PIPELINE_STATUS_TIMEOUT_MINUTES",
+		"mode":            "PIPELINE_STATUS_MODE",
+	}
+	for name, want := range tests {
+		if got := flagEnvName(name); got != want {
+			t.Errorf("flagEnvName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWithoutEmptyFlags(t *testing.T) {
+	args := []string{"-checkNames=", "-mode=collect-all", "--policy=", "-retries", "e2e-*=2", "-x==", "--", "-timeoutMinutes="}
+	want := []string{"-mode=collect-all", "-retries", "e2e-*=2", "-x==", "--", "-timeoutMinutes="}
+	if got := withoutEmptyFlags(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestEscapePattern(t *testing.T) {
+	if got, want := escapePattern(`e2e[chrome]*?\`), `e2e\[chrome]\*\?\\`; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
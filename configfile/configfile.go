@@ -0,0 +1,363 @@
+// Package configfile reads the optional YAML file that a repository can configure the action with, usually
+// .github/pipeline-status.yml. Its schema is published as pipeline-status.schema.json at the root of this repository.
+package configfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tamj0rd2/pipeline-status-action/policy"
+)
+
+// DefaultPath is where the file is looked for when no path is given.
+const DefaultPath = ".github/pipeline-status.yml"
+
+// Version is the only schema version this build understands. It only goes up when a change would make an existing
+// file mean something different.
+const Version = 1
+
+// DefaultNotifier is the notifier that the slackWebhookURL flag sets, and that alerts go to when no route matches.
+const DefaultNotifier = "default"
+
+const notifierTypeSlack = "slack"
+
+// The modes the wait can run in. They match the values of the mode flag.
+const (
+	modeFailFast   = "fail-fast"
+	modeCollectAll = "collect-all"
+)
+
+// severities are the values a check's severity can have. They match the values of the severities flag. Only required
+// checks can fail the wait.
+var severities = []string{"required", "warn", "info"}
+
+type File struct {
+	Version  int       `yaml:"version"`
+	Checks   []Check   `yaml:"checks"`
+	Patterns []Pattern `yaml:"patterns"`
+	Timeouts Timeouts  `yaml:"timeouts"`
+	Stages   []Stage   `yaml:"stages"`
+	// Policy is an expression the checks must satisfy instead of all of them succeeding, e.g
+	// build AND (e2e-chrome OR e2e-firefox).
+	Policy string `yaml:"policy"`
+	// Mode is fail-fast or collect-all. Empty leaves it to the flag.
+	Mode string `yaml:"mode"`
+	// EarlyAlerts is nil if the file doesn't say, so that false can still override a true from the central file.
+	EarlyAlerts *bool               `yaml:"earlyAlerts"`
+	Notifiers   map[string]Notifier `yaml:"notifiers"`
+	Routes      []Route             `yaml:"routes"`
+}
+
+// Check is a check to wait for. In the file it can be just the name of the check, or a mapping with a name.
+type Check struct {
+	Name     string        `yaml:"name"`
+	Timeouts CheckTimeouts `yaml:"timeouts"`
+	// Severity is required, warn or info. Empty leaves it to patterns and the flag, and then it's required.
+	Severity string `yaml:"severity"`
+}
+
+func (c *Check) UnmarshalYAML(unmarshal func(any) error) error {
+	if err := unmarshal(&c.Name); err == nil {
+		return nil
+	}
+
+	// an alias doesn't have this method, so decoding into it doesn't recurse.
+	type check Check
+	return unmarshal((*check)(c))
+}
+
+// Pattern applies settings to every check whose name matches Match, which uses path.Match syntax, e.g e2e-*.
+type Pattern struct {
+	Match string `yaml:"match"`
+	// Retries is nil if the pattern doesn't say how many times to retry, so that it doesn't stop a later pattern that
+	// does from applying.
+	Retries  *int          `yaml:"retries"`
+	Timeouts CheckTimeouts `yaml:"timeouts"`
+	Severity string        `yaml:"severity"`
+}
+
+// CheckTimeouts are deadlines for a check, written like 90s or 5m. A check that misses one is given up on straight
+// away rather than at the overall timeout.
+type CheckTimeouts struct {
+	// AppearWithin is how long after waiting began the check must have been reported by.
+	AppearWithin time.Duration `yaml:"appearWithin"`
+	// FinishWithin is how long after it started the check must have finished by. A re-run starts the clock again.
+	FinishWithin time.Duration `yaml:"finishWithin"`
+	// ExpectedDuration is how long the check usually takes. Taking longer is only logged and mentioned in alerts.
+	ExpectedDuration time.Duration `yaml:"expectedDuration"`
+}
+
+func (t CheckTimeouts) IsZero() bool {
+	return t == CheckTimeouts{}
+}
+
+func (t CheckTimeouts) validate() error {
+	if t.AppearWithin < 0 || t.FinishWithin < 0 || t.ExpectedDuration < 0 {
+		return errors.New("timeouts must not be negative")
+	}
+	return nil
+}
+
+// Stage is a named group of checks, given by name or path.Match pattern. Stages are listed in the order they run.
+type Stage struct {
+	Name   string   `yaml:"name"`
+	Checks []string `yaml:"checks"`
+}
+
+type Timeouts struct {
+	// WaitMinutes is how long to wait for all the checks before giving up.
+	WaitMinutes int `yaml:"waitMinutes"`
+}
+
+// Notifier is somewhere alerts can be sent. The webhook URL is a secret, so it's usually better to name the
+// environment variable that holds it with WebhookURLEnv than to commit it as WebhookURL.
+type Notifier struct {
+	Type          string `yaml:"type"`
+	WebhookURL    string `yaml:"webhookURL"`
+	WebhookURLEnv string `yaml:"webhookURLEnv"`
+}
+
+// URL returns the webhook URL of the notifier, which is empty if it's taken from an environment variable that isn't
+// set.
+func (n Notifier) URL() string {
+	if n.WebhookURLEnv != "" {
+		return os.Getenv(n.WebhookURLEnv)
+	}
+	return n.WebhookURL
+}
+
+// Route sends alerts about the failed checks whose names match any of Checks to every one of Notifiers. A route
+// without checks matches every check. Each failed check only follows the first route it matches.
+type Route struct {
+	Checks    []string `yaml:"checks"`
+	Notifiers []string `yaml:"notifiers"`
+}
+
+func (r Route) Matches(checkName string) bool {
+	if len(r.Checks) == 0 {
+		return true
+	}
+
+	for _, pattern := range r.Checks {
+		if matched, _ := path.Match(pattern, checkName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads the file at the given path. The returned bool is false if the path is the default one and there's no
+// file there, because the file is optional unless a path was asked for.
+func Load(filePath string) (File, bool, error) {
+	explicit := filePath != ""
+	if !explicit {
+		filePath = DefaultPath
+	}
+
+	f, err := os.Open(filePath)
+	if !explicit && errors.Is(err, os.ErrNotExist) {
+		return File{}, false, nil
+	}
+	if err != nil {
+		return File{}, false, err
+	}
+	defer f.Close()
+
+	file, err := Parse(f)
+	if err == nil {
+		err = file.CheckReferences()
+	}
+	if err != nil {
+		return File{}, false, fmt.Errorf("%s is invalid - %w", filePath, err)
+	}
+	return file, true, nil
+}
+
+// Parse decodes and validates a file. Keys that aren't in the schema are errors rather than being ignored, so that
+// typos don't silently change nothing.
+func Parse(r io.Reader) (File, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var file File
+	if err := decoder.Decode(&file); err != nil {
+		if errors.Is(err, io.EOF) {
+			return File{}, errors.New("the file is empty")
+		}
+		return File{}, err
+	}
+
+	if err := file.Validate(); err != nil {
+		return File{}, err
+	}
+	return file, nil
+}
+
+func (f File) Validate() error {
+	if f.Version == 0 {
+		return errors.New("version is required")
+	}
+	if f.Version != Version {
+		return fmt.Errorf("version %d isn't supported, the only supported version is %d", f.Version, Version)
+	}
+
+	seen := make(map[string]bool)
+	for i, check := range f.Checks {
+		if strings.TrimSpace(check.Name) == "" {
+			return fmt.Errorf("checks[%d] needs a name", i)
+		}
+		if strings.Contains(check.Name, ",") {
+			return fmt.Errorf("checks[%d] can't contain a comma, got %q", i, check.Name)
+		}
+		if seen[check.Name] {
+			return fmt.Errorf("check %q is listed more than once", check.Name)
+		}
+		if err := check.Timeouts.validate(); err != nil {
+			return fmt.Errorf("check %q %w", check.Name, err)
+		}
+		if !check.Timeouts.IsZero() && strings.Contains(check.Name, "=") {
+			return fmt.Errorf("check %q can't have timeouts because its name contains an equals sign", check.Name)
+		}
+		if err := validateSeverity(check.Severity); err != nil {
+			return fmt.Errorf("check %q %w", check.Name, err)
+		}
+		if check.Severity != "" && strings.Contains(check.Name, "=") {
+			return fmt.Errorf("check %q can't have a severity because its name contains an equals sign", check.Name)
+		}
+		seen[check.Name] = true
+	}
+
+	for i, pattern := range f.Patterns {
+		if pattern.Match == "" {
+			return fmt.Errorf("patterns[%d] needs a match", i)
+		}
+		if _, err := path.Match(pattern.Match, ""); err != nil {
+			return fmt.Errorf("patterns[%d] has an invalid match %q - %w", i, pattern.Match, err)
+		}
+		if strings.ContainsAny(pattern.Match, ",=") {
+			return fmt.Errorf("patterns[%d] match can't contain a comma or equals sign, got %q", i, pattern.Match)
+		}
+		if pattern.Retries != nil && *pattern.Retries < 0 {
+			return fmt.Errorf("patterns[%d] retries must not be negative", i)
+		}
+		if err := pattern.Timeouts.validate(); err != nil {
+			return fmt.Errorf("patterns[%d] %w", i, err)
+		}
+		if err := validateSeverity(pattern.Severity); err != nil {
+			return fmt.Errorf("patterns[%d] %w", i, err)
+		}
+	}
+
+	if f.Timeouts.WaitMinutes < 0 {
+		return errors.New("timeouts.waitMinutes must not be negative")
+	}
+
+	stageNames := make(map[string]bool)
+	for i, stage := range f.Stages {
+		if strings.TrimSpace(stage.Name) == "" {
+			return fmt.Errorf("stages[%d] needs a name", i)
+		}
+		if strings.ContainsAny(stage.Name, "=;") {
+			return fmt.Errorf("stages[%d] name can't contain an equals sign or semicolon, got %q", i, stage.Name)
+		}
+		if stageNames[stage.Name] {
+			return fmt.Errorf("stage %q is listed more than once", stage.Name)
+		}
+		stageNames[stage.Name] = true
+
+		if len(stage.Checks) == 0 {
+			return fmt.Errorf("stage %q needs at least one check", stage.Name)
+		}
+		for _, pattern := range stage.Checks {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("stage %q has an invalid check pattern %q - %w", stage.Name, pattern, err)
+			}
+			if strings.TrimSpace(pattern) == "" || strings.ContainsAny(pattern, ",;") {
+				return fmt.Errorf("stage %q check patterns can't be empty or contain a comma or semicolon, got %q", stage.Name, pattern)
+			}
+		}
+	}
+
+	if f.Policy != "" {
+		if _, err := policy.Parse(f.Policy); err != nil {
+			return fmt.Errorf("policy is invalid - %w", err)
+		}
+	}
+
+	if f.Mode != "" && f.Mode != modeFailFast && f.Mode != modeCollectAll {
+		return fmt.Errorf("mode must be %s or %s, got %q", modeFailFast, modeCollectAll, f.Mode)
+	}
+
+	for _, name := range f.NotifierNames() {
+		notifier := f.Notifiers[name]
+		if notifier.Type != "" && notifier.Type != notifierTypeSlack {
+			return fmt.Errorf("notifier %q has an unsupported type %q, the only supported type is %s", name, notifier.Type, notifierTypeSlack)
+		}
+		if (notifier.WebhookURL == "") == (notifier.WebhookURLEnv == "") {
+			return fmt.Errorf("notifier %q needs exactly one of webhookURL or webhookURLEnv", name)
+		}
+	}
+
+	for i, route := range f.Routes {
+		for _, pattern := range route.Checks {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("routes[%d] has an invalid check pattern %q - %w", i, pattern, err)
+			}
+		}
+
+		if len(route.Notifiers) == 0 {
+			return fmt.Errorf("routes[%d] needs at least one notifier", i)
+		}
+	}
+
+	return nil
+}
+
+func validateSeverity(severity string) error {
+	if severity != "" && !slices.Contains(severities, severity) {
+		return fmt.Errorf("severity must be one of %s, got %q", strings.Join(severities, ", "), severity)
+	}
+	return nil
+}
+
+// CheckReferences makes sure that every notifier a route sends to is defined. It's separate from Validate because a
+// repository's file can send to notifiers that are only defined in the central file, so it's checked once they've
+// been merged.
+func (f File) CheckReferences() error {
+	for i, route := range f.Routes {
+		for _, name := range route.Notifiers {
+			if _, ok := f.Notifiers[name]; !ok && name != DefaultNotifier {
+				return fmt.Errorf("routes[%d] sends to notifier %q, which isn't defined", i, name)
+			}
+		}
+	}
+	return nil
+}
+
+// NotifierNames returns the names of the notifiers, sorted so that they're always handled in the same order.
+func (f File) NotifierNames() []string {
+	names := make([]string, 0, len(f.Notifiers))
+	for name := range f.Notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckNames returns the names of the checks in the order they're listed.
+func (f File) CheckNames() []string {
+	names := make([]string, 0, len(f.Checks))
+	for _, check := range f.Checks {
+		names = append(names, check.Name)
+	}
+	return names
+}
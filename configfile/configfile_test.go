@@ -0,0 +1,160 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	file, err := Parse(strings.NewReader(`
+version: 1
+checks:
+  - build
+  - name: e2e-chrome
+    severity: warn
+    timeouts:
+      finishWithin: 50m
+patterns:
+  - match: e2e-*
+    retries: 2
+timeouts:
+  waitMinutes: 45
+stages:
+  - name: build
+    checks: [build]
+policy: build AND e2e-chrome optional
+mode: collect-all
+earlyAlerts: false
+notifiers:
+  default:
+    webhookURLEnv: SLACK_WEBHOOK_URL
+routes:
+  - checks: [e2e-*]
+    notifiers: [default]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	retries, earlyAlerts := 2, false
+	want := File{
+		Version: 1,
+		Checks: []Check{
+			{Name: "build"},
+			{Name: "e2e-chrome", Severity: "warn", Timeouts: CheckTimeouts{FinishWithin: 50 * time.Minute}},
+		},
+		Patterns:    []Pattern{{Match: "e2e-*", Retries: &retries}},
+		Timeouts:    Timeouts{WaitMinutes: 45},
+		Stages:      []Stage{{Name: "build", Checks: []string{"build"}}},
+		Policy:      "build AND e2e-chrome optional",
+		Mode:        "collect-all",
+		EarlyAlerts: &earlyAlerts,
+		Notifiers:   map[string]Notifier{"default": {WebhookURLEnv: "SLACK_WEBHOOK_URL"}},
+		Routes:      []Route{{Checks: []string{"e2e-*"}, Notifiers: []string{"default"}}},
+	}
+	if !reflect.DeepEqual(file, want) {
+		t.Errorf("expected %+v, got %+v", want, file)
+	}
+	if got := file.CheckNames(); !reflect.DeepEqual(got, []string{"build", "e2e-chrome"}) {
+		t.Errorf("expected the check names in order, got %v", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, file, want string
+	}{
+		{name: "empty", file: "", want: "the file is empty"},
+		{name: "unknown key", file: "version: 1\ncheckNames: [build]", want: "field checkNames not found"},
+		{name: "no version", file: "checks: [build]", want: "version is required"},
+		{name: "future version", file: "version: 2", want: "version 2 isn't supported"},
+		{name: "duplicate check", file: "version: 1\nchecks: [build, build]", want: `check "build" is listed more than once`},
+		{name: "comma in check", file: "version: 1\nchecks: ['a,b']", want: "can't contain a comma"},
+		{name: "negative timeout", file: "version: 1\nchecks: [{name: build, timeouts: {appearWithin: -1m}}]", want: `check "build" timeouts must not be negative`},
+		{name: "unknown severity", file: "version: 1\nchecks: [{name: build, severity: fatal}]", want: `severity must be one of required, warn, info, got "fatal"`},
+		{name: "invalid pattern", file: "version: 1\npatterns: [{match: '[', retries: 1}]", want: "patterns[0] has an invalid match"},
+		{name: "negative retries", file: "version: 1\npatterns: [{match: e2e-*, retries: -1}]", want: "patterns[0] retries must not be negative"},
+		{name: "stage without checks", file: "version: 1\nstages: [{name: build}]", want: `stage "build" needs at least one check`},
+		{name: "duplicate stage", file: "version: 1\nstages: [{name: a, checks: [x]}, {name: a, checks: [y]}]", want: `stage "a" is listed more than once`},
+		{name: "invalid policy", file: "version: 1\npolicy: build AND", want: "policy is invalid"},
+		{name: "unknown mode", file: "version: 1\nmode: eventually", want: `mode must be fail-fast or collect-all, got "eventually"`},
+		{name: "notifier with both URLs", file: "version: 1\nnotifiers: {default: {webhookURL: https://example.com, webhookURLEnv: URL}}", want: `notifier "default" needs exactly one of webhookURL or webhookURLEnv`},
+		{name: "unsupported notifier", file: "version: 1\nnotifiers: {teams: {type: teams, webhookURL: https://example.com}}", want: `unsupported type "teams"`},
+		{name: "route without notifiers", file: "version: 1\nroutes: [{checks: [build]}]", want: "routes[0] needs at least one notifier"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.file))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %q", tt.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("a missing default file is fine", func(t *testing.T) {
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chdir(t.TempDir()); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = os.Chdir(wd) })
+
+		_, found, err := Load("")
+		if err != nil || found {
+			t.Errorf("expected nothing to be found, got %v and %v", found, err)
+		}
+	})
+
+	t.Run("a missing explicit file is an error", func(t *testing.T) {
+		if _, _, err := Load(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("routes must send to defined notifiers", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yml")
+		if err := os.WriteFile(path, []byte("version: 1\nroutes: [{notifiers: [frontend]}]"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err := Load(path)
+		if err == nil || !strings.Contains(err.Error(), `sends to notifier "frontend", which isn't defined`) {
+			t.Errorf("expected an undefined notifier error, got %v", err)
+		}
+	})
+}
+
+func TestNotifierURL(t *testing.T) {
+	t.Setenv("TEST_SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/from-env")
+
+	if got := (Notifier{WebhookURLEnv: "TEST_SLACK_WEBHOOK_URL"}).URL(); got != "https://hooks.slack.com/services/from-env" {
+		t.Errorf("expected the URL from the environment, got %q", got)
+	}
+	if got := (Notifier{WebhookURL: "https://hooks.slack.com/services/inline"}).URL(); got != "https://hooks.slack.com/services/inline" {
+		t.Errorf("expected the inline URL, got %q", got)
+	}
+}
+
+func TestRouteMatches(t *testing.T) {
+	route := Route{Checks: []string{"e2e-*", "lint"}}
+	for name, want := range map[string]bool{"e2e-chrome": true, "lint": true, "build": false} {
+		if got := route.Matches(name); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	if !(Route{}).Matches("anything") {
+		t.Error("expected a route without checks to match every check")
+	}
+}
@@ -0,0 +1,95 @@
+package configfile
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Merge layers a repository's own file over the central one. Checks, timeouts and other settings that the repository
+// sets replace the central ones, its patterns and routes are tried before the central ones, and notifiers are merged
+// by name. Merging an empty file changes nothing.
+func Merge(central, repo File) File {
+	merged := central
+	if repo.Version != 0 {
+		merged.Version = repo.Version
+	}
+
+	if len(repo.Checks) > 0 {
+		merged.Checks = repo.Checks
+	}
+
+	if repo.Timeouts.WaitMinutes != 0 {
+		merged.Timeouts.WaitMinutes = repo.Timeouts.WaitMinutes
+	}
+
+	if len(repo.Stages) > 0 {
+		merged.Stages = repo.Stages
+	}
+
+	if repo.Policy != "" {
+		merged.Policy = repo.Policy
+	}
+
+	if repo.Mode != "" {
+		merged.Mode = repo.Mode
+	}
+
+	if repo.EarlyAlerts != nil {
+		merged.EarlyAlerts = repo.EarlyAlerts
+	}
+
+	merged.Patterns = append(append([]Pattern(nil), repo.Patterns...), central.Patterns...)
+	merged.Routes = append(append([]Route(nil), repo.Routes...), central.Routes...)
+
+	if len(repo.Notifiers) > 0 {
+		merged.Notifiers = make(map[string]Notifier, len(central.Notifiers)+len(repo.Notifiers))
+		for name, notifier := range central.Notifiers {
+			merged.Notifiers[name] = notifier
+		}
+		for name, notifier := range repo.Notifiers {
+			merged.Notifiers[name] = notifier
+		}
+	}
+
+	return merged
+}
+
+// CheckRepoNotifiers returns an error if a repository's file reads the webhook URL of a notifier from an environment
+// variable that none of the central file's notifiers read. The environment is the central process's, so a repository
+// can only use the webhook URLs it was given rather than any variable it likes.
+func CheckRepoNotifiers(central, repo File) error {
+	for _, name := range repo.NotifierNames() {
+		if env := repo.Notifiers[name].WebhookURLEnv; env != "" && !central.readsEnv(env) {
+			return fmt.Errorf("notifier %q can't read $%s, a repository's file can only read the environment variables that the central file's notifiers do", name, env)
+		}
+	}
+	return nil
+}
+
+func (f File) readsEnv(env string) bool {
+	for _, notifier := range f.Notifiers {
+		if notifier.WebhookURLEnv == env {
+			return true
+		}
+	}
+	return false
+}
+
+// RecoverNotifier reads a single notifier from a repository's file that might be invalid, so that problems with the
+// file can still be reported to the channel it was meant to configure. Like CheckRepoNotifiers, it only reads the
+// environment variables that the central file's notifiers do.
+func RecoverNotifier(central File, content []byte, name string) (Notifier, bool) {
+	var file struct {
+		Notifiers map[string]Notifier `yaml:"notifiers"`
+	}
+	// anything that can't be read is ignored, because all that matters is whether the notifier can be.
+	_ = yaml.NewDecoder(bytes.NewReader(content)).Decode(&file)
+
+	notifier, ok := file.Notifiers[name]
+	if !ok || notifier.URL() == "" || (notifier.WebhookURLEnv != "" && !central.readsEnv(notifier.WebhookURLEnv)) {
+		return Notifier{}, false
+	}
+	return notifier, true
+}
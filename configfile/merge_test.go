@@ -0,0 +1,119 @@
+package configfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	centralEarlyAlerts, repoEarlyAlerts := true, false
+	central := File{
+		Version:     1,
+		Checks:      []Check{{Name: "build"}},
+		Patterns:    []Pattern{{Match: "*", Severity: "required"}},
+		Timeouts:    Timeouts{WaitMinutes: 60},
+		Policy:      "build",
+		Mode:        "fail-fast",
+		EarlyAlerts: &centralEarlyAlerts,
+		Notifiers: map[string]Notifier{
+			"default":  {WebhookURLEnv: "SLACK_WEBHOOK_URL"},
+			"frontend": {WebhookURLEnv: "FRONTEND_SLACK_WEBHOOK_URL"},
+		},
+		Routes: []Route{{Notifiers: []string{"default"}}},
+	}
+	repo := File{
+		Version:     1,
+		Checks:      []Check{{Name: "test"}},
+		Patterns:    []Pattern{{Match: "perf-*", Severity: "info"}},
+		Stages:      []Stage{{Name: "test", Checks: []string{"test"}}},
+		Mode:        "collect-all",
+		EarlyAlerts: &repoEarlyAlerts,
+		Notifiers:   map[string]Notifier{"default": {WebhookURLEnv: "FRONTEND_SLACK_WEBHOOK_URL"}},
+		Routes:      []Route{{Checks: []string{"e2e-*"}, Notifiers: []string{"frontend"}}},
+	}
+
+	merged := Merge(central, repo)
+
+	want := File{
+		Version:     1,
+		Checks:      []Check{{Name: "test"}},
+		Patterns:    []Pattern{{Match: "perf-*", Severity: "info"}, {Match: "*", Severity: "required"}},
+		Timeouts:    Timeouts{WaitMinutes: 60},
+		Stages:      []Stage{{Name: "test", Checks: []string{"test"}}},
+		Policy:      "build",
+		Mode:        "collect-all",
+		EarlyAlerts: &repoEarlyAlerts,
+		Notifiers: map[string]Notifier{
+			"default":  {WebhookURLEnv: "FRONTEND_SLACK_WEBHOOK_URL"},
+			"frontend": {WebhookURLEnv: "FRONTEND_SLACK_WEBHOOK_URL"},
+		},
+		Routes: []Route{{Checks: []string{"e2e-*"}, Notifiers: []string{"frontend"}}, {Notifiers: []string{"default"}}},
+	}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("expected %+v, got %+v", want, merged)
+	}
+
+	if central.Notifiers["default"].WebhookURLEnv != "SLACK_WEBHOOK_URL" {
+		t.Error("expected merging not to change the central file's notifiers")
+	}
+}
+
+func TestMergeEmptyRepoFile(t *testing.T) {
+	central := File{
+		Version:   1,
+		Checks:    []Check{{Name: "build"}},
+		Patterns:  []Pattern{{Match: "e2e-*"}},
+		Notifiers: map[string]Notifier{"default": {WebhookURL: "https://hooks.slack.com/services/central"}},
+	}
+
+	if merged := Merge(central, File{}); !reflect.DeepEqual(merged, central) {
+		t.Errorf("expected merging an empty file to change nothing, got %+v", merged)
+	}
+}
+
+func TestCheckRepoNotifiers(t *testing.T) {
+	central := File{Notifiers: map[string]Notifier{"default": {WebhookURLEnv: "SLACK_WEBHOOK_URL"}}}
+
+	allowed := File{Notifiers: map[string]Notifier{
+		"default": {WebhookURLEnv: "SLACK_WEBHOOK_URL"},
+		"inline":  {WebhookURL: "https://hooks.slack.com/services/inline"},
+	}}
+	if err := CheckRepoNotifiers(central, allowed); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	other := File{Notifiers: map[string]Notifier{"default": {WebhookURLEnv: "GITHUB_TOKEN"}}}
+	err := CheckRepoNotifiers(central, other)
+	if err == nil || !strings.Contains(err.Error(), `notifier "default" can't read $GITHUB_TOKEN`) {
+		t.Errorf("expected an error about reading $GITHUB_TOKEN, got %v", err)
+	}
+}
+
+func TestRecoverNotifier(t *testing.T) {
+	t.Setenv("SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/central")
+	t.Setenv("SECRET", "not a webhook")
+	central := File{Notifiers: map[string]Notifier{"default": {WebhookURLEnv: "SLACK_WEBHOOK_URL"}}}
+
+	tests := []struct {
+		name, content string
+		ok            bool
+	}{
+		{name: "from an invalid file", content: "version: 99\nnotifiers:\n  default:\n    webhookURLEnv: SLACK_WEBHOOK_URL\nchecks: {", ok: false},
+		{name: "from a file with other mistakes", content: "version: 99\nnotifiers:\n  default:\n    webhookURLEnv: SLACK_WEBHOOK_URL\n", ok: true},
+		{name: "inline", content: "notifiers:\n  default:\n    webhookURL: https://hooks.slack.com/services/inline\n", ok: true},
+		{name: "missing", content: "notifiers:\n  frontend:\n    webhookURL: https://hooks.slack.com/services/inline\n", ok: false},
+		{name: "undeclared environment variable", content: "notifiers:\n  default:\n    webhookURLEnv: SECRET\n", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, ok := RecoverNotifier(central, []byte(tt.content), DefaultNotifier)
+			if ok != tt.ok {
+				t.Fatalf("expected ok to be %v, got %v with %+v", tt.ok, ok, notifier)
+			}
+			if ok && notifier.URL() == "" {
+				t.Error("expected the recovered notifier to have a URL")
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package dora computes the DORA metrics of a branch from the outcomes of the checks on each of its commits. A commit
+// counts as deployed once all of its tracked checks have succeeded, and as a failed change if any of them failed.
+package dora
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/github"
+)
+
+// Commit is a commit on the branch along with the final state of its tracked checks.
+type Commit struct {
+	SHA         string
+	CommittedAt time.Time
+	Statuses    []github.Status
+}
+
+type Metrics struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	Commits     int `json:"commits"`
+	Deployments int `json:"deployments"`
+	Failures    int `json:"failures"`
+	// Incomplete commits have checks that hadn't finished, or were never reported, and are left out of the other
+	// metrics.
+	Incomplete int `json:"incomplete"`
+
+	DeploymentsPerDay float64 `json:"deploymentsPerDay"`
+	// ChangeFailureRate is the fraction of finished commits that had a failed check.
+	ChangeFailureRate float64 `json:"changeFailureRate"`
+	// LeadTime is how long it took from a commit being made to all of its checks succeeding.
+	LeadTime Durations `json:"leadTime"`
+	// TimeToRestore is how long it took from the first failure to the next commit whose checks all succeeded.
+	TimeToRestore Durations `json:"timeToRestore"`
+}
+
+type Durations struct {
+	Count  int
+	Mean   time.Duration
+	Median time.Duration
+}
+
+func (d Durations) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Count         int     `json:"count"`
+		MeanSeconds   float64 `json:"meanSeconds"`
+		MedianSeconds float64 `json:"medianSeconds"`
+	}{d.Count, d.Mean.Seconds(), d.Median.Seconds()})
+}
+
+type commitResult int
+
+const (
+	incomplete commitResult = iota
+	green
+	red
+)
+
+func result(commit Commit) (commitResult, time.Time) {
+	var lastCompletion, firstFailure time.Time
+	failed, unfinished := false, false
+	for _, status := range commit.Statuses {
+		switch status.State() {
+		case github.StateFailure:
+			if !failed || status.CompletedAt.Before(firstFailure) {
+				firstFailure = status.CompletedAt
+			}
+			failed = true
+		case github.StateSuccess:
+			if status.CompletedAt.After(lastCompletion) {
+				lastCompletion = status.CompletedAt
+			}
+		default:
+			unfinished = true
+		}
+	}
+
+	// a failure makes the commit red even if other checks haven't finished, whichever order the checks are in.
+	switch {
+	case failed:
+		return red, firstFailure
+	case unfinished:
+		return incomplete, time.Time{}
+	default:
+		return green, lastCompletion
+	}
+}
+
+// Compute calculates the metrics for commits made between from and to. The commits must be ordered oldest first.
+func Compute(commits []Commit, from, to time.Time) Metrics {
+	metrics := Metrics{From: from, To: to, Commits: len(commits)}
+
+	var leadTimes, restoreTimes []time.Duration
+	var brokenAt time.Time
+	for _, commit := range commits {
+		res, at := result(commit)
+		switch res {
+		case incomplete:
+			metrics.Incomplete++
+		case green:
+			metrics.Deployments++
+			leadTimes = append(leadTimes, nonNegative(at.Sub(commit.CommittedAt)))
+
+			if !brokenAt.IsZero() {
+				restoreTimes = append(restoreTimes, nonNegative(at.Sub(brokenAt)))
+				brokenAt = time.Time{}
+			}
+		case red:
+			metrics.Failures++
+			if brokenAt.IsZero() {
+				brokenAt = at
+			}
+		}
+	}
+
+	if days := to.Sub(from).Hours() / 24; days > 0 {
+		metrics.DeploymentsPerDay = float64(metrics.Deployments) / days
+	}
+
+	if finished := metrics.Deployments + metrics.Failures; finished > 0 {
+		metrics.ChangeFailureRate = float64(metrics.Failures) / float64(finished)
+	}
+
+	metrics.LeadTime = summarise(leadTimes)
+	metrics.TimeToRestore = summarise(restoreTimes)
+	return metrics
+}
+
+func summarise(durations []time.Duration) Durations {
+	if len(durations) == 0 {
+		return Durations{}
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+
+	return Durations{Count: len(sorted), Mean: total / time.Duration(len(sorted)), Median: median}
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,65 @@
+package dora
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/github"
+)
+
+func succeeded(name string, at time.Time) github.Status {
+	return github.Status{Name: name, Finished: true, Succeeded: true, StartedAt: at.Add(-time.Minute), CompletedAt: at}
+}
+
+func failed(name string, at time.Time) github.Status {
+	return github.Status{Name: name, Finished: true, StartedAt: at.Add(-time.Minute), CompletedAt: at}
+}
+
+func pending(name string, startedAt time.Time) github.Status {
+	return github.Status{Name: name, StartedAt: startedAt}
+}
+
+func TestCompute(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	hours := func(n int) time.Time { return from.Add(time.Duration(n) * time.Hour) }
+
+	commits := []Commit{
+		{SHA: "a", CommittedAt: hours(0), Statuses: []github.Status{succeeded("build", hours(1))}},
+		// a failure makes a commit red whether it comes before or after a check that hasn't finished.
+		{SHA: "b", CommittedAt: hours(2), Statuses: []github.Status{failed("build", hours(3)), pending("test", hours(2))}},
+		{SHA: "c", CommittedAt: hours(4), Statuses: []github.Status{pending("build", hours(4)), failed("test", hours(5))}},
+		{SHA: "d", CommittedAt: hours(6), Statuses: []github.Status{pending("build", hours(6))}},
+		{SHA: "e", CommittedAt: hours(7), Statuses: []github.Status{succeeded("build", hours(9)), succeeded("test", hours(10))}},
+	}
+
+	got := Compute(commits, from, from.Add(48*time.Hour))
+
+	want := Metrics{
+		From:              from,
+		To:                from.Add(48 * time.Hour),
+		Commits:           5,
+		Deployments:       2,
+		Failures:          2,
+		Incomplete:        1,
+		DeploymentsPerDay: 1,
+		ChangeFailureRate: 0.5,
+		// lead time is from the commit to its last check succeeding, so 1h for a and 3h for e.
+		LeadTime: Durations{Count: 2, Mean: 2 * time.Hour, Median: 2 * time.Hour},
+		// the branch broke when b's build failed at 3h, and was restored when e's last check succeeded at 10h.
+		TimeToRestore: Durations{Count: 1, Mean: 7 * time.Hour, Median: 7 * time.Hour},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestComputeWithNoCommits(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	got := Compute(nil, from, from.Add(24*time.Hour))
+
+	want := Metrics{From: from, To: from.Add(24 * time.Hour)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
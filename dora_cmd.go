@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/dora"
+	"github.com/tamj0rd2/pipeline-status-action/github"
+)
+
+func runDORA(args []string) error {
+	flags := newFlagSet("dora")
+	token := flags.String("token", "", "GitHub token. Defaults to $GITHUB_TOKEN")
+	repository := flags.String("repository", "", "GitHub repository, e.g owner/repo")
+	branch := flags.String("branch", "", "The branch to compute the metrics for. Defaults to the repository's default branch")
+	checkNames := flags.String("checkNames", "", "A comma separated list of the checks that make up the pipeline")
+	since := flags.String("since", "", "The first day to include, e.g 2022-08-01. Defaults to 30 days ago")
+	until := flags.String("until", "", "The day after the last day to include, e.g 2022-09-01. Defaults to now")
+	format := flags.String("format", formatTable, "The output format, table or json")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	*token = orEnv(*token, "GITHUB_TOKEN")
+	if *token == "" {
+		return usageErrorf("token is required")
+	}
+
+	owner, repoName, err := splitRepository(*repository)
+	if err != nil {
+		return err
+	}
+
+	checks := splitList(*checkNames)
+	if len(checks) == 0 {
+		return usageErrorf("checkNames is required")
+	}
+
+	to := time.Now().UTC()
+	if *until != "" {
+		if to, err = parseDate("until", *until); err != nil {
+			return err
+		}
+	}
+
+	from := to.AddDate(0, 0, -30)
+	if *since != "" {
+		if from, err = parseDate("since", *since); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	service := github.NewService(ctx, *token)
+
+	if *branch == "" {
+		if *branch, err = service.DefaultBranch(ctx, owner, repoName); err != nil {
+			return err
+		}
+	}
+
+	branchCommits, err := service.ListBranchCommits(ctx, owner, repoName, *branch, from, to)
+	if err != nil {
+		return err
+	}
+
+	commits := make([]dora.Commit, 0, len(branchCommits))
+	for _, branchCommit := range branchCommits {
+		statuses, err := service.Snapshot(ctx, owner, repoName, branchCommit.SHA, checks)
+		if err != nil {
+			return err
+		}
+		slog.Debug("got checks for commit", "sha", branchCommit.SHA)
+		commits = append(commits, dora.Commit{SHA: branchCommit.SHA, CommittedAt: branchCommit.CommittedAt, Statuses: statuses})
+	}
+
+	metrics := dora.Compute(commits, from, to)
+
+	switch *format {
+	case formatTable:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Period\t%s to %s (%s)\n", from.Format("2006-01-02"), to.Format("2006-01-02"), *branch)
+		fmt.Fprintf(w, "Commits\t%d (%d deployed, %d failed, %d incomplete)\n", metrics.Commits, metrics.Deployments, metrics.Failures, metrics.Incomplete)
+		fmt.Fprintf(w, "Deployment frequency\t%.2f per day\n", metrics.DeploymentsPerDay)
+		fmt.Fprintf(w, "Lead time\t%s median, %s mean\n", roundDuration(metrics.LeadTime.Median), roundDuration(metrics.LeadTime.Mean))
+		fmt.Fprintf(w, "Change failure rate\t%.1f%%\n", metrics.ChangeFailureRate*100)
+		fmt.Fprintf(w, "Time to restore\t%s median, %s mean (%d restores)\n", roundDuration(metrics.TimeToRestore.Median), roundDuration(metrics.TimeToRestore.Mean), metrics.TimeToRestore.Count)
+		return w.Flush()
+	case formatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(metrics)
+	default:
+		return usageErrorf("format must be %s or %s", formatTable, formatJSON)
+	}
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	return d.Round(time.Second)
+}
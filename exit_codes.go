@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/tamj0rd2/pipeline-status-action/github"
+	"github.com/tamj0rd2/pipeline-status-action/slack"
+)
+
+// Exit codes are documented in the README, so calling workflows can branch on why the gate failed. Don't change the
+// meaning of an existing code.
+const (
+	exitSuccess       = 0
+	exitChecksFailed  = 1
+	exitInvalidUsage  = 2
+	exitTimedOut      = 3
+	exitChecksMissing = 4
+	exitAPIError      = 5
+	exitAuthError     = 6
+	exitNotifierError = 7
+	exitError         = 8
+)
+
+func exitCode(err error) int {
+	var (
+		checksFailedErr  github.ChecksFailedError
+		timedOutErr      github.TimedOutError
+		checksMissingErr github.ChecksMissingError
+		authErr          github.AuthError
+		apiErr           github.APIError
+		notifierErr      slack.NotifierError
+		usageErr         usageError
+	)
+
+	switch {
+	case err == nil:
+		return exitSuccess
+	case errors.As(err, &checksFailedErr):
+		return exitChecksFailed
+	case errors.As(err, &timedOutErr):
+		return exitTimedOut
+	case errors.As(err, &checksMissingErr):
+		return exitChecksMissing
+	case errors.As(err, &authErr):
+		return exitAuthError
+	case errors.As(err, &apiErr):
+		return exitAPIError
+	case errors.As(err, &notifierErr):
+		return exitNotifierError
+	case errors.As(err, &usageErr):
+		return exitInvalidUsage
+	default:
+		return exitError
+	}
+}
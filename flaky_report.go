@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/github"
+	"github.com/tamj0rd2/pipeline-status-action/history"
+)
+
+func recordOutcomes(store *history.Store, config config, statuses []github.Status) error {
+	now := time.Now().UTC()
+	repository := config.owner + "/" + config.repoName
+
+	var outcomes []history.Outcome
+	for _, status := range statuses {
+		for attempt := 1; attempt <= status.Retries; attempt++ {
+			outcomes = append(outcomes, history.Outcome{Repository: repository, SHA: config.sha, Check: status.Name, Attempt: attempt, Succeeded: false, RecordedAt: now})
+		}
+
+		if status.Finished {
+			outcomes = append(outcomes, history.Outcome{Repository: repository, SHA: config.sha, Check: status.Name, Attempt: status.Retries + 1, Succeeded: status.Succeeded, RecordedAt: now})
+		}
+	}
+
+	return store.AppendOutcomes(outcomes...)
+}
+
+func runFlakyReport(args []string) error {
+	flags := newFlagSet("flaky-report")
+	historyFile := flags.String("historyFile", "", "The file that check outcomes were recorded in")
+	repository := flags.String("repository", "", "Only include checks from this repository, e.g owner/repo")
+	days := flags.Int("days", 30, "The number of days of history to include")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *historyFile == "" {
+		return usageErrorf("historyFile is required")
+	}
+
+	outcomes, err := history.NewStore(*historyFile).Outcomes()
+	if err != nil {
+		return err
+	}
+
+	if *repository != "" {
+		outcomes = history.ForRepository(outcomes, *repository)
+	}
+
+	stats := history.FlakeStats(outcomes, time.Now().AddDate(0, 0, -*days))
+	if len(stats) == 0 {
+		fmt.Printf("no flaky checks in the last %d days\n", *days)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tFLAKE RATE\tFLAKES\tCOMMITS")
+	for _, stat := range stats {
+		fmt.Fprintf(w, "%s\t%.1f%%\t%d\t%d\n", stat.Check, stat.Rate()*100, stat.Flakes, stat.Commits)
+	}
+	return w.Flush()
+}
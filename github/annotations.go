@@ -0,0 +1,75 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/go-github/v42/github"
+)
+
+// Annotation is a file level message that a linter or test reporter attached to a check run.
+type Annotation struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Level     string
+	Title     string
+	Message   string
+	// URL links to the annotated lines in the commit being tracked.
+	URL string
+}
+
+var annotationLevelOrder = map[string]int{"failure": 0, "warning": 1, "notice": 2}
+
+// AttachAnnotations adds up to limit annotations to each of the given statuses that are backed by a check run,
+// most severe first.
+func (s Service) AttachAnnotations(ctx context.Context, owner, repo, sha string, statuses []Status, limit int) ([]Status, []error) {
+	var errs []error
+	for i, status := range statuses {
+		checkRunID := status.CheckRunID
+		if checkRunID == 0 {
+			// the job ID of a GitHub Actions job is the same as the ID of its check run.
+			jobID, ok := actionsJobID(status.Url)
+			if !ok {
+				continue
+			}
+			checkRunID = jobID
+		}
+
+		annotations, _, err := s.client.Checks.ListCheckRunAnnotations(ctx, owner, repo, checkRunID, &github.ListOptions{PerPage: 100})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get annotations for %s - %w", status.Name, err))
+			continue
+		}
+
+		sort.SliceStable(annotations, func(a, b int) bool {
+			return annotationLevelOrder[annotations[a].GetAnnotationLevel()] < annotationLevelOrder[annotations[b].GetAnnotationLevel()]
+		})
+
+		for j, annotation := range annotations {
+			if j == limit {
+				break
+			}
+			statuses[i].Annotations = append(statuses[i].Annotations, newAnnotation(owner, repo, sha, annotation))
+		}
+	}
+	return statuses, errs
+}
+
+func newAnnotation(owner, repo, sha string, annotation *github.CheckRunAnnotation) Annotation {
+	url := fmt.Sprintf("https://github.com/%s/%s/blob/%s/%s#L%d", owner, repo, sha, annotation.GetPath(), annotation.GetStartLine())
+	if annotation.GetEndLine() > annotation.GetStartLine() {
+		url += fmt.Sprintf("-L%d", annotation.GetEndLine())
+	}
+
+	return Annotation{
+		Path:      annotation.GetPath(),
+		StartLine: annotation.GetStartLine(),
+		EndLine:   annotation.GetEndLine(),
+		Level:     annotation.GetAnnotationLevel(),
+		Title:     annotation.GetTitle(),
+		Message:   annotation.GetMessage(),
+		URL:       url,
+	}
+}
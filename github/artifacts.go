@@ -0,0 +1,155 @@
+package github
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/google/go-github/v42/github"
+
+	"github.com/tamj0rd2/pipeline-status-action/testreport"
+)
+
+var actionsRunURLPattern = regexp.MustCompile(`/actions/runs/(\d+)`)
+
+// maxArtifactSize stops a huge artifact from being pulled into memory just to look for test reports.
+const maxArtifactSize = 100 * 1024 * 1024
+
+// workflowRunID returns the ID of the workflow run behind a status target URL, if it points at a GitHub Actions job.
+func (s Service) workflowRunID(ctx context.Context, owner, repo, targetURL string) (int64, bool, error) {
+	if match := actionsRunURLPattern.FindStringSubmatch(targetURL); match != nil {
+		id, err := strconv.ParseInt(match[1], 10, 64)
+		return id, err == nil, nil
+	}
+
+	jobID, ok := actionsJobID(targetURL)
+	if !ok {
+		return 0, false, nil
+	}
+
+	job, _, err := s.client.Actions.GetWorkflowJobByID(ctx, owner, repo, jobID)
+	if err != nil {
+		return 0, false, err
+	}
+	return job.GetRunID(), true, nil
+}
+
+// AttachTestFailures downloads the named artifacts from the workflow runs behind the given statuses and adds any
+// failed tests found in the JUnit XML or `go test -json` reports inside them.
+func (s Service) AttachTestFailures(ctx context.Context, owner, repo string, statuses []Status, artifactNames []string) ([]Status, []error) {
+	wanted := make(map[string]bool)
+	for _, name := range artifactNames {
+		wanted[name] = true
+	}
+
+	var errs []error
+	for i, status := range statuses {
+		runID, ok, err := s.workflowRunID(ctx, owner, repo, status.Url)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to find the workflow run for %s - %w", status.Name, err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		artifacts, _, err := s.client.Actions.ListWorkflowRunArtifacts(ctx, owner, repo, runID, &github.ListOptions{PerPage: 100})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list artifacts for %s - %w", status.Name, err))
+			continue
+		}
+
+		for _, artifact := range artifacts.Artifacts {
+			if !wanted[artifact.GetName()] {
+				continue
+			}
+
+			// the failures from the reports that could be read are kept even if others in the artifact couldn't be.
+			failures, err := s.getArtifactTestFailures(ctx, owner, repo, artifact)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to read test reports from artifact %s for %s - %w", artifact.GetName(), status.Name, err))
+			}
+			statuses[i].FailedTests = append(statuses[i].FailedTests, failures...)
+		}
+	}
+	return statuses, errs
+}
+
+func (s Service) getArtifactTestFailures(ctx context.Context, owner, repo string, artifact *github.Artifact) ([]testreport.Failure, error) {
+	if artifact.GetSizeInBytes() > maxArtifactSize {
+		return nil, fmt.Errorf("artifact is larger than %d bytes", maxArtifactSize)
+	}
+
+	downloadURL, _, err := s.client.Actions.DownloadArtifact(ctx, owner, repo, artifact.GetID(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxArtifactSize))
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return archiveTestFailures(archive)
+}
+
+// archiveTestFailures reads the failures from every test report in the archive. Files that aren't test reports, like
+// coverage reports, are skipped, and so are reports that can't be read, so that one bad file doesn't hide the
+// failures in the others. The problems with the reports that couldn't be read are returned alongside the failures.
+func archiveTestFailures(archive *zip.Reader) ([]testreport.Failure, error) {
+	var (
+		failures []testreport.Failure
+		errs     []error
+	)
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		fileFailures, err := parseZippedTestReport(file)
+		if errors.Is(err, testreport.ErrUnsupportedFormat) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s - %w", file.Name, err))
+			continue
+		}
+		failures = append(failures, fileFailures...)
+	}
+	return failures, errors.Join(errs...)
+}
+
+func parseZippedTestReport(file *zip.File) ([]testreport.Failure, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return testreport.Parse(file.Name, r)
+}
@@ -0,0 +1,69 @@
+package github
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tamj0rd2/pipeline-status-action/testreport"
+)
+
+// zipFile is a file to put in a zip archive, in order.
+type zipFile struct {
+	name, content string
+}
+
+func zipArchive(t *testing.T, files []zipFile) *zip.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, file := range files {
+		f, err := w.Create(file.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(file.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestArchiveTestFailuresFromAMixedArtifact(t *testing.T) {
+	archive := zipArchive(t, []zipFile{
+		{name: "coverage.xml", content: `<?xml version="1.0"?><coverage line-rate="0.8"><packages/></coverage>`},
+		{name: "package.json", content: "{\n  \"name\": \"shop\"\n}\n"},
+		{name: "reports/"},
+		{name: "reports/broken.xml", content: `<testsuite name="cut short"><testcase`},
+		{name: "reports/junit.xml", content: `<testsuite name="checkout"><testcase name="pays"><failure message="expected 200, got 500"/></testcase></testsuite>`},
+		{name: "reports/go-test.json", content: `{"Action":"fail","Package":"example.com/shop","Test":"TestSearch"}`},
+	})
+
+	failures, err := archiveTestFailures(archive)
+
+	want := []testreport.Failure{
+		{Suite: "checkout", Name: "pays", Message: "expected 200, got 500"},
+		{Suite: "example.com/shop", Name: "TestSearch"},
+	}
+	if !reflect.DeepEqual(failures, want) {
+		t.Errorf("expected the failures from the readable reports %+v, got %+v", want, failures)
+	}
+
+	if err == nil || !strings.Contains(err.Error(), "reports/broken.xml") {
+		t.Errorf("expected an error about the broken report only, got %v", err)
+	}
+	if err != nil && (strings.Contains(err.Error(), "coverage.xml") || strings.Contains(err.Error(), "package.json")) {
+		t.Errorf("expected files that aren't test reports to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v42/github"
+)
+
+// GetFileContent returns the content of a file in a repository at the given ref. The returned bool is false if there's
+// no such file.
+func (s Service) GetFileContent(ctx context.Context, owner, repo, ref, path string) ([]byte, bool, error) {
+	file, _, res, err := s.client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if res != nil && res.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, newAPIError(fmt.Errorf("failed to get %s - %w", path, err))
+	}
+	if file == nil {
+		return nil, false, newAPIError(fmt.Errorf("failed to get %s - it's a directory", path))
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, false, newAPIError(fmt.Errorf("failed to decode %s - %w", path, err))
+	}
+	return []byte(content), true, nil
+}
@@ -0,0 +1,129 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CheckTimeouts are deadlines for the checks whose names match Pattern, which uses path.Match syntax, e.g e2e-*. A
+// zero duration isn't enforced.
+type CheckTimeouts struct {
+	Pattern string
+	// AppearWithin is how long after waiting began the check must have been reported by.
+	AppearWithin time.Duration
+	// FinishWithin is how long after it started, according to GitHub, the check must have finished by. A check that's
+	// re-run gets the whole of FinishWithin again, counted from when it was re-run.
+	FinishWithin time.Duration
+	// ExpectedDuration is how long the check usually takes. Taking longer is only logged and mentioned in alerts.
+	ExpectedDuration time.Duration
+}
+
+// The deadlines a check can miss. DeadlineWait is the overall timeout of the wait.
+const (
+	DeadlineAppear = "appear"
+	DeadlineFinish = "finish"
+	DeadlineWait   = "wait"
+)
+
+func findCheckTimeouts(timeouts []CheckTimeouts, checkName string) (CheckTimeouts, bool) {
+	for _, t := range timeouts {
+		if matched, _ := path.Match(t.Pattern, checkName); matched {
+			return t, true
+		}
+	}
+	return CheckTimeouts{}, false
+}
+
+// MissedDeadlineText says which deadline the check missed, or is empty if it didn't miss one.
+func (status Status) MissedDeadlineText() string {
+	switch status.MissedDeadline {
+	case DeadlineAppear:
+		return fmt.Sprintf("didn't appear within %s", status.MissedDeadlineAfter)
+	case DeadlineFinish:
+		return fmt.Sprintf("didn't finish within %s", status.MissedDeadlineAfter)
+	case DeadlineWait:
+		return fmt.Sprintf("wasn't done when the %s timeout was reached", status.MissedDeadlineAfter)
+	default:
+		return ""
+	}
+}
+
+// Overran reports whether the check has taken longer than it's expected to.
+func (status Status) Overran() bool {
+	return status.ExpectedDuration > 0 && status.Duration() > status.ExpectedDuration
+}
+
+// enforceDeadlines marks every unfinished check that has missed one of its deadlines, and returns them. Checks that
+// have just started taking longer than expected are logged.
+func (t statusTracker) enforceDeadlines(ctx context.Context, opts WaitOptions, waitStartedAt, now time.Time) []Status {
+	var missed []Status
+	for name, status := range t {
+		if status.Finished || status.MissedDeadline != "" {
+			continue
+		}
+
+		timeouts, ok := findCheckTimeouts(opts.CheckTimeouts, name)
+		if !ok {
+			continue
+		}
+		status.ExpectedDuration = timeouts.ExpectedDuration
+
+		switch {
+		case status.State() == StateMissing && timeouts.AppearWithin > 0 && now.Sub(waitStartedAt) > timeouts.AppearWithin:
+			status.MissedDeadline, status.MissedDeadlineAfter = DeadlineAppear, timeouts.AppearWithin
+		case status.State() == StatePending && timeouts.FinishWithin > 0 && now.Sub(status.attemptStartedAt) > timeouts.FinishWithin:
+			status.MissedDeadline, status.MissedDeadlineAfter = DeadlineFinish, timeouts.FinishWithin
+		}
+
+		if status.Overran() && !status.overranLogged {
+			opts.logger().WarnContext(ctx, "check is taking longer than expected", "check", name, "expected", timeouts.ExpectedDuration, "running", status.Duration().Round(time.Second))
+			status.overranLogged = true
+		}
+
+		t[name] = status
+		if status.MissedDeadline != "" {
+			missed = append(missed, status)
+		}
+	}
+
+	sort.Slice(missed, func(i, j int) bool { return missed[i].Name < missed[j].Name })
+	return missed
+}
+
+// markTimedOut records that every unfinished check was still going when the overall timeout was reached.
+func (t statusTracker) markTimedOut(timeout time.Duration) {
+	for name, status := range t {
+		if !status.Finished && status.MissedDeadline == "" && !status.Skipped {
+			status.MissedDeadline, status.MissedDeadlineAfter = DeadlineWait, timeout
+			t[name] = status
+		}
+	}
+}
+
+// deadlineError lists the deadlines that checks missed.
+type deadlineError struct {
+	missed []Status
+}
+
+func (e deadlineError) Error() string {
+	var reasons []string
+	for _, status := range e.missed {
+		reasons = append(reasons, status.Name+" "+status.MissedDeadlineText())
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// MissedDeadlineStatuses returns the statuses that were given up on because they missed a deadline.
+func MissedDeadlineStatuses(statuses []Status) []Status {
+	var missed []Status
+	for _, status := range statuses {
+		if status.MissedDeadline != "" {
+			missed = append(missed, status)
+		}
+	}
+	return missed
+}
@@ -0,0 +1,151 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v42/github"
+)
+
+// ChecksFailedError is returned when at least one tracked check finished unsuccessfully, or when the gate policy
+// failed. Clause is the part of the policy that failed, and Checks the checks in it that failed.
+type ChecksFailedError struct {
+	Checks []string
+	Clause string
+}
+
+func (e ChecksFailedError) Error() string {
+	if e.Clause == "" {
+		return fmt.Sprintf("one or more checks failed - %s", strings.Join(e.Checks, ", "))
+	}
+
+	if len(e.Checks) == 0 {
+		return fmt.Sprintf("the gate policy failed at %s", e.Clause)
+	}
+	return fmt.Sprintf("the gate policy failed at %s - %s", e.Clause, strings.Join(e.Checks, ", "))
+}
+
+// TimedOutError is returned when the timeout was reached while some checks were still running. Checks lists every
+// check that was given up on, and Pending and Missing split them into those that were running and those that were
+// never reported.
+type TimedOutError struct {
+	Checks  []string
+	Pending []string
+	Missing []string
+	// Suggestions are the reported checks that the missing ones might have been meant to be, by name.
+	Suggestions map[string][]string
+	Err         error
+}
+
+func (e TimedOutError) Error() string {
+	var parts []string
+	if len(e.Pending) > 0 {
+		parts = append(parts, "still running: "+strings.Join(e.Pending, ", "))
+	}
+	if len(e.Missing) > 0 {
+		parts = append(parts, "never reported: "+missingText(e.Missing, e.Suggestions))
+	}
+	return fmt.Sprintf("timed out waiting for checks to start/complete - %s: %s", strings.Join(parts, "; "), e.Err)
+}
+
+func (e TimedOutError) Unwrap() error {
+	return e.Err
+}
+
+// ChecksMissingError is returned when the timeout was reached and none of the unfinished checks were ever reported.
+// That usually means a check name is wrong rather than that the pipeline is slow.
+type ChecksMissingError struct {
+	Checks []string
+	// Suggestions are the reported checks that the missing ones might have been meant to be, by name.
+	Suggestions map[string][]string
+	Err         error
+}
+
+func (e ChecksMissingError) Error() string {
+	return fmt.Sprintf("timed out waiting for checks that were never reported - %s: %s", missingText(e.Checks, e.Suggestions), e.Err)
+}
+
+func missingText(missing []string, suggestions map[string][]string) string {
+	var names []string
+	for _, name := range missing {
+		if len(suggestions[name]) > 0 {
+			name += " (did you mean " + strings.Join(suggestions[name], " or ") + "?)"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (e ChecksMissingError) Unwrap() error {
+	return e.Err
+}
+
+// APIError is returned when the GitHub API couldn't be used to get the state of the checks.
+type APIError struct {
+	Err error
+}
+
+func (e APIError) Error() string {
+	return fmt.Sprintf("github api error - %s", e.Err)
+}
+
+func (e APIError) Unwrap() error {
+	return e.Err
+}
+
+// AuthError is an APIError caused by the token being invalid or not having the required permissions.
+type AuthError struct {
+	Err error
+}
+
+func (e AuthError) Error() string {
+	return fmt.Sprintf("github authentication error - %s", e.Err)
+}
+
+func (e AuthError) Unwrap() error {
+	return e.Err
+}
+
+// newAPIError wraps err in an AuthError if GitHub rejected the token, or an APIError otherwise.
+func newAPIError(err error) error {
+	var rateLimitErr *github.RateLimitError
+	var abuseRateLimitErr *github.AbuseRateLimitError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseRateLimitErr) {
+		return APIError{Err: err}
+	}
+
+	var errorResponse *github.ErrorResponse
+	if errors.As(err, &errorResponse) && errorResponse.Response != nil {
+		switch errorResponse.Response.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return AuthError{Err: err}
+		}
+	}
+
+	return APIError{Err: err}
+}
+
+// newTimeoutError returns a ChecksMissingError if none of the incomplete checks were ever seen, or a TimedOutError
+// otherwise.
+func newTimeoutError(incomplete []Status, err error) error {
+	var names, pending, missing []string
+	suggestions := make(map[string][]string)
+	for _, status := range incomplete {
+		names = append(names, status.Name)
+		if status.State() == StateMissing {
+			missing = append(missing, status.Name)
+			if len(status.Suggestions) > 0 {
+				suggestions[status.Name] = status.Suggestions
+			}
+		} else {
+			pending = append(pending, status.Name)
+		}
+	}
+
+	if len(pending) == 0 && len(missing) > 0 {
+		return ChecksMissingError{Checks: names, Suggestions: suggestions, Err: err}
+	}
+	return TimedOutError{Checks: names, Pending: pending, Missing: missing, Suggestions: suggestions, Err: err}
+}
@@ -0,0 +1,125 @@
+package github
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	actionsJobURLPattern = regexp.MustCompile(`/actions/runs/(\d+)/jobs?/(\d+)`)
+	// checkRunURLPattern only matches the URL of a check run, like https://github.com/owner/repo/runs/123, and not
+	// that of a workflow run, like https://github.com/owner/repo/actions/runs/123, whose ID isn't a job's.
+	checkRunURLPattern  = regexp.MustCompile(`^https://github\.com/[^/]+/[^/]+/runs/(\d+)(?:[/?#]|$)`)
+	logTimestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z `)
+)
+
+const (
+	logErrorMarker = "##[error]"
+	logGroupMarker = "##[group]"
+)
+
+// actionsJobID returns the ID of the GitHub Actions job that a status target URL points at, if it points at one.
+func actionsJobID(targetURL string) (int64, bool) {
+	if match := actionsJobURLPattern.FindStringSubmatch(targetURL); match != nil {
+		id, err := strconv.ParseInt(match[2], 10, 64)
+		return id, err == nil
+	}
+
+	if match := checkRunURLPattern.FindStringSubmatch(targetURL); match != nil {
+		id, err := strconv.ParseInt(match[1], 10, 64)
+		return id, err == nil
+	}
+
+	return 0, false
+}
+
+// AttachLogExcerpts downloads the job logs behind any statuses that are backed by GitHub Actions jobs, and adds the
+// failing step and the lines leading up to the first error to them. Statuses whose logs can't be retrieved are
+// returned unchanged.
+func (s Service) AttachLogExcerpts(ctx context.Context, owner, repo string, statuses []Status, lines int) ([]Status, []error) {
+	var errs []error
+	for i, status := range statuses {
+		jobID, ok := actionsJobID(status.Url)
+		if !ok {
+			continue
+		}
+
+		step, excerpt, err := s.getJobLogExcerpt(ctx, owner, repo, jobID, lines)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get logs for %s - %w", status.Name, err))
+			continue
+		}
+
+		statuses[i].FailedStep = step
+		statuses[i].LogExcerpt = excerpt
+	}
+	return statuses, errs
+}
+
+func (s Service) getJobLogExcerpt(ctx context.Context, owner, repo string, jobID int64, lines int) (string, string, error) {
+	logsURL, _, err := s.client.Actions.GetWorkflowJobLogs(ctx, owner, repo, jobID, true)
+	if err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logsURL.String(), nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	step, excerpt, err := extractLogExcerpt(res.Body, lines)
+	return step, excerpt, err
+}
+
+// extractLogExcerpt finds the first error in a job log and returns the name of the step it happened in along with up
+// to the given number of lines leading up to and including it.
+func extractLogExcerpt(r io.Reader, lines int) (string, string, error) {
+	var (
+		step   string
+		recent []string
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := logTimestampPattern.ReplaceAllString(strings.TrimPrefix(scanner.Text(), "\ufeff"), "")
+
+		if strings.HasPrefix(line, logGroupMarker) {
+			step = strings.TrimPrefix(line, logGroupMarker)
+			recent = recent[:0]
+			continue
+		}
+
+		recent = append(recent, line)
+		if len(recent) > lines {
+			recent = recent[1:]
+		}
+
+		if strings.HasPrefix(line, logErrorMarker) {
+			recent[len(recent)-1] = strings.TrimPrefix(line, logErrorMarker)
+			return step, strings.Join(recent, "\n"), nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+
+	return "", "", fmt.Errorf("no %s marker found in the job log", logErrorMarker)
+}
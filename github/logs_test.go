@@ -0,0 +1,74 @@
+package github
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestActionsJobID(t *testing.T) {
+	tests := []struct {
+		url    string
+		want   int64
+		wantOK bool
+	}{
+		{url: "https://github.com/o/r/actions/runs/111/job/222", want: 222, wantOK: true},
+		{url: "https://github.com/o/r/actions/runs/111/jobs/222?pr=3", want: 222, wantOK: true},
+		{url: "https://github.com/o/r/runs/333", want: 333, wantOK: true},
+		{url: "https://github.com/o/r/runs/333?check_suite_focus=true", want: 333, wantOK: true},
+		{url: "https://github.com/o/r/actions/runs/111", wantOK: false},
+		{url: "https://github.com/o/r/runs/333abc", wantOK: false},
+		{url: "https://ci.example.com/job/222", wantOK: false},
+		{url: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := actionsJobID(tt.url)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("actionsJobID(%q) = %d, %v, want %d, %v", tt.url, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestExtractLogExcerpt(t *testing.T) {
+	log := "\ufeff2024-03-01T10:00:00.1234567Z ##[group]Run actions/checkout@v4\n" +
+		"2024-03-01T10:00:01.0000000Z checked out\n" +
+		"2024-03-01T10:00:02.0000000Z ##[group]Run go test ./...\n" +
+		"2024-03-01T10:00:03.0000000Z ok   example.com/shop/api\n" +
+		"2024-03-01T10:00:04.0000000Z --- FAIL: TestCheckout\n" +
+		"2024-03-01T10:00:05.0000000Z     shop_test.go:12: expected 2 items, got 1\n" +
+		"2024-03-01T10:00:06.0000000Z ##[error]Process completed with exit code 1.\n" +
+		"2024-03-01T10:00:07.0000000Z ##[error]a later error\n"
+
+	t.Run("the lines before the first error in its step", func(t *testing.T) {
+		step, excerpt, err := extractLogExcerpt(strings.NewReader(log), 3)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if step != "Run go test ./..." {
+			t.Errorf("expected the step the error happened in, got %q", step)
+		}
+
+		want := "--- FAIL: TestCheckout\n    shop_test.go:12: expected 2 items, got 1\nProcess completed with exit code 1."
+		if excerpt != want {
+			t.Errorf("expected %q, got %q", want, excerpt)
+		}
+	})
+
+	t.Run("lines from earlier steps are left out", func(t *testing.T) {
+		_, excerpt, err := extractLogExcerpt(strings.NewReader(log), 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Contains(excerpt, "checked out") {
+			t.Errorf("expected only lines from the failing step, got %q", excerpt)
+		}
+		if !strings.HasPrefix(excerpt, "ok   example.com/shop/api") {
+			t.Errorf("expected the excerpt to start at the beginning of the step, got %q", excerpt)
+		}
+	})
+
+	t.Run("a log without an error", func(t *testing.T) {
+		if _, _, err := extractLogExcerpt(strings.NewReader("2024-03-01T10:00:00Z all good\n"), 3); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+)
+
+// RetryPolicy re-runs the failed jobs of checks whose names match Pattern up to MaxRetries times before they count
+// as failed. Pattern uses path.Match syntax, e.g e2e-*.
+type RetryPolicy struct {
+	Pattern    string `json:"pattern"`
+	MaxRetries int    `json:"maxRetries"`
+}
+
+func findRetryPolicy(policies []RetryPolicy, checkName string) (RetryPolicy, bool) {
+	for _, policy := range policies {
+		if matched, _ := path.Match(policy.Pattern, checkName); matched {
+			return policy, true
+		}
+	}
+	return RetryPolicy{}, false
+}
+
+// retryFailedChecks re-runs the failed jobs behind the given checks and marks them as pending again. Nothing is
+// re-run unless every failed check can be, because a single check that can't be retried fails the wait anyway.
+func (s Service) retryFailedChecks(ctx context.Context, owner, repo string, tracker statusTracker, failedChecks []Status, opts WaitOptions) (bool, error) {
+	runIDs := make(map[string]int64)
+	for _, status := range failedChecks {
+		policy, ok := findRetryPolicy(opts.RetryPolicies, status.Name)
+		if !ok || status.Retries >= policy.MaxRetries {
+			return false, nil
+		}
+
+		runID, ok, err := s.workflowRunID(ctx, owner, repo, status.Url)
+		if err != nil {
+			return false, fmt.Errorf("failed to find the workflow run for %s - %w", status.Name, err)
+		}
+		if !ok {
+			return false, fmt.Errorf("can't retry %s because it isn't a GitHub Actions job", status.Name)
+		}
+		runIDs[status.Name] = runID
+	}
+
+	rerun := make(map[int64]bool)
+	for _, status := range failedChecks {
+		runID := runIDs[status.Name]
+		if !rerun[runID] {
+			if err := s.rerunFailedJobs(ctx, owner, repo, runID); err != nil {
+				return false, fmt.Errorf("failed to re-run %s - %w", status.Name, err)
+			}
+			rerun[runID] = true
+		}
+
+		opts.logger().InfoContext(ctx, "check failed, re-running its failed jobs", "check", status.Name, "attempt", status.Retries+2, "runID", runID)
+		tracker[status.Name] = status.resetForRetry()
+	}
+
+	return true, nil
+}
+
+func (s Service) rerunFailedJobs(ctx context.Context, owner, repo string, runID int64) error {
+	req, err := s.client.NewRequest("POST", fmt.Sprintf("repos/%v/%v/actions/runs/%v/rerun-failed-jobs", owner, repo, runID), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(ctx, req, nil)
+	return err
+}
+
+// resetForRetry starts a new attempt at the check, which has until its finish deadline from now rather than from
+// when the first attempt started.
+func (status Status) resetForRetry() Status {
+	now := time.Now()
+	return Status{
+		Name:        status.Name,
+		Url:         status.Url,
+		StartedAt:   status.StartedAt,
+		FirstSeenAt: status.FirstSeenAt,
+		Transitions: append(status.Transitions, Transition{State: StateRetrying, At: now}),
+		Retries:     status.Retries + 1,
+		Severity:    status.Severity,
+		Stage:       status.Stage,
+		retriedID:   status.observedID,
+
+		attemptStartedAt: now,
+	}
+}
@@ -2,80 +2,432 @@ package github
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v42/github"
 	"golang.org/x/oauth2"
+
+	"github.com/tamj0rd2/pipeline-status-action/actions"
+	"github.com/tamj0rd2/pipeline-status-action/policy"
+	"github.com/tamj0rd2/pipeline-status-action/testreport"
+	"github.com/tamj0rd2/pipeline-status-action/tracing"
 )
 
 type Service struct {
-	client *github.Client
+	client    *github.Client
+	transport *countingTransport
 }
 
 func NewService(ctx context.Context, githubToken string) *Service {
 	httpClient := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: githubToken}))
+	transport := &countingTransport{base: httpClient.Transport}
+	httpClient.Transport = transport
 	client := github.NewClient(httpClient)
 
 	return &Service{
-		client: client,
+		client:    client,
+		transport: transport,
 	}
 }
 
-func (s Service) WaitForChecksToSucceed(ctx context.Context, timeout time.Duration, owner string, repo string, sha string, checkNames []string) ([]Status, error) {
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+// APIStats returns the number of GitHub API requests made so far.
+func (s Service) APIStats() APIStats {
+	return s.transport.Stats()
+}
+
+// WaitOptions controls which checks WaitForChecksToSucceed waits for and how it reacts to them.
+type WaitOptions struct {
+	Timeout       time.Duration
+	CheckNames    []string
+	RetryPolicies []RetryPolicy
+	// CheckTimeouts are deadlines for individual checks. The first one that matches a check applies to it.
+	CheckTimeouts []CheckTimeouts
+	// Mode is ModeFailFast, which is the default, or ModeCollectAll.
+	Mode string
+	// Severities say which checks can fail the wait. The first one that matches a check applies to it, and checks
+	// that none match are required.
+	Severities []CheckSeverity
+	// Stages are the order the checks run in. Once a stage fails, the checks in the stages after it aren't waited for.
+	Stages []Stage
+	// Policy decides whether the checks passed, if set, instead of every check having to succeed. Its checks are
+	// tracked along with CheckNames, and the wait stops as soon as the policy is decided unless in collect-all mode.
+	Policy *policy.Policy
+	// OnCheckGivenUp is called in collect-all mode as soon as a check has failed for good or missed a deadline,
+	// while the other checks are still being waited for.
+	OnCheckGivenUp func(ctx context.Context, status Status)
+	// Tracer records the wait as a trace, if set.
+	Tracer *tracing.Tracer
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+	// PollInterval is how long to wait between checking on the checks, which defaults to defaultPollInterval.
+	PollInterval time.Duration
+}
+
+const defaultPollInterval = 30 * time.Second
+
+func (opts WaitOptions) logger() *slog.Logger {
+	if opts.Logger == nil {
+		return slog.Default()
+	}
+	return opts.Logger
+}
+
+func (opts WaitOptions) pollInterval() time.Duration {
+	if opts.PollInterval <= 0 {
+		return defaultPollInterval
+	}
+	return opts.PollInterval
+}
+
+// The modes WaitForChecksToSucceed can run in. Fail-fast stops as soon as any required check fails, and
+// collect-all keeps waiting until every required and warn check has either succeeded or been given up on, so that the
+// whole picture can be reported.
+const (
+	ModeFailFast   = "fail-fast"
+	ModeCollectAll = "collect-all"
+)
+
+// WaitForChecksToSucceed polls until every required and warn check has succeeded, a required one has failed (or, in
+// collect-all mode, every required and warn check has finished or been given up on) or the timeout is reached. Info
+// checks aren't waited for beyond that, and only required checks still running at the timeout make it an error. The
+// state of every tracked check is returned either way. Use FailedStatuses and IncompleteStatuses to pick out the
+// problems.
+func (s Service) WaitForChecksToSucceed(ctx context.Context, owner string, repo string, sha string, opts WaitOptions) ([]Status, error) {
+	root := opts.Tracer.Start("WaitForChecksToSucceed", nil, time.Now())
+	root.SetAttribute("repository", owner+"/"+repo)
+	root.SetAttribute("sha", sha)
+
+	statuses, err := s.waitForChecksToSucceed(tracing.ContextWithSpan(ctx, root), owner, repo, sha, opts)
+	err = withStage(err, statuses, opts.Stages)
+	traceChecks(opts.Tracer, root, statuses, err)
+	return statuses, err
+}
+
+func (s Service) waitForChecksToSucceed(ctx context.Context, owner string, repo string, sha string, opts WaitOptions) ([]Status, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
 	defer cancel()
 
-	const sleepTimeSeconds = 30
-	statusTracker := newStatusTracker(checkNames)
+	statusTracker := newStatusTracker(opts.trackedChecks())
+	statusTracker.setSeverities(opts.Severities)
+	statusTracker.setStages(opts.Stages)
+	startedAt := time.Now()
 
 	for {
 		if err := ctx.Err(); err != nil {
-			return statusTracker.GetIncompleteChecks(), fmt.Errorf("timed out waiting for checks to start/complete: %w", err)
+			return s.timedOut(ctx, owner, repo, sha, statusTracker, opts, err)
 		}
 
 		if err := s.check(ctx, owner, repo, sha, statusTracker); err != nil {
-			return nil, fmt.Errorf("failed to get statuses for commit - %w", err)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return s.timedOut(ctx, owner, repo, sha, statusTracker, opts, ctxErr)
+			}
+			return statusTracker.All(), newAPIError(fmt.Errorf("failed to get statuses for commit - %w", err))
+		}
+
+		// with a policy, one failed check doesn't fail the wait, so each check is dealt with on its own like in
+		// collect-all mode.
+		perCheck := opts.Mode == ModeCollectAll || opts.Policy != nil
+
+		if failedChecks := statusTracker.newlyFailedChecks(); len(failedChecks) > 0 {
+			// checks that aren't required can't fail the wait, so they're always dealt with on their own.
+			required, nonBlocking := splitRequired(failedChecks)
+			if perCheck {
+				required, nonBlocking = nil, failedChecks
+			}
+			if len(nonBlocking) > 0 {
+				s.retryOrGiveUp(ctx, owner, repo, statusTracker, nonBlocking, opts)
+			}
+
+			if len(required) > 0 {
+				retried, err := s.retryFailedChecks(ctx, owner, repo, statusTracker, required, opts)
+				if err != nil {
+					opts.logger().WarnContext(ctx, "failed to retry checks", "error", err)
+				}
+
+				if !retried {
+					return statusTracker.All(), ChecksFailedError{Checks: StatusNames(RequiredStatuses(statusTracker.GetFailedChecks()))}
+				}
+			}
 		}
 
-		if failedChecks := statusTracker.GetFailedChecks(); len(failedChecks) > 0 {
-			return statusTracker.GetFailedChecks(), errors.New("one or more checks failed")
+		// info checks can't change the result or be alerted about, so they aren't waited for once the others are done.
+		if opts.Policy == nil && statusTracker.requiredSucceeded() && statusTracker.warnDecided() {
+			return statusTracker.All(), nil
 		}
 
-		if statusTracker.AllCompletedSuccessfully() {
-			return nil, nil
+		// a check that has missed its own deadline is given up on straight away, rather than at the overall timeout.
+		if missed := statusTracker.enforceDeadlines(ctx, opts, startedAt, time.Now()); len(missed) > 0 {
+			required, nonBlocking := splitRequired(missed)
+			if perCheck {
+				required, nonBlocking = nil, missed
+			}
+
+			if len(required) > 0 {
+				s.attachSuggestions(ctx, owner, repo, sha, statusTracker, opts)
+				missed = RequiredStatuses(MissedDeadlineStatuses(statusTracker.All()))
+				return statusTracker.All(), newTimeoutError(missed, deadlineError{missed: missed})
+			}
+
+			for _, status := range nonBlocking {
+				statusTracker.giveUp(ctx, status.Name, opts)
+			}
 		}
 
-		checksInProgress := statusTracker.GetIncompleteChecks()
-		var checksInProgressName []string
-		for _, status := range checksInProgress {
-			checksInProgressName = append(checksInProgressName, status.Name)
+		if len(opts.Stages) > 0 {
+			statusTracker.skipLaterStages(ctx, opts)
+		}
+
+		if opts.Policy != nil {
+			decided, err := statusTracker.decide(opts.Policy)
+			done := false
+			switch {
+			case !decided:
+			case opts.Mode == ModeCollectAll:
+				done = statusTracker.requiredDecided() && statusTracker.warnDecided()
+			case err != nil:
+				done = true
+			default:
+				// a policy that passed still waits for its optional checks and warn checks, so that they can be reported.
+				done = statusTracker.optionalDecided(opts.Policy) && statusTracker.warnDecided()
+			}
+			if done {
+				if err != nil {
+					s.attachSuggestions(ctx, owner, repo, sha, statusTracker, opts)
+				}
+				opts.logger().InfoContext(ctx, "gate policy decided", "policy", opts.Policy.String(), "error", err)
+				return statusTracker.All(), err
+			}
+		} else if opts.Mode == ModeCollectAll && statusTracker.requiredDecided() && statusTracker.warnDecided() {
+			s.attachSuggestions(ctx, owner, repo, sha, statusTracker, opts)
+			return statusTracker.All(), statusTracker.collectedError(nil)
 		}
 
-		log.Printf(
-			"waiting for some checks to start and/or complete - %s. will check again in %d seconds\n",
-			strings.Join(checksInProgressName, ", "),
-			sleepTimeSeconds,
+		checksInProgress := statusTracker.waitingOn()
+		checksInProgressName := StatusNames(checksInProgress)
+		tracing.SpanFromContext(ctx).AddEvent("poll", time.Now(), map[string]any{
+			"checks.incomplete": len(checksInProgress),
+			"checks.total":      len(statusTracker),
+		})
+
+		actions.Group(fmt.Sprintf("Waiting for %d of %d checks", len(checksInProgress), len(statusTracker)))
+		for _, status := range checksInProgress {
+			opts.logger().InfoContext(ctx, "waiting for check", "check", status.Name, "state", status.State(), "attempt", status.Retries+1)
+		}
+		opts.logger().InfoContext(ctx, "waiting for some checks to start and/or complete",
+			"checks", strings.Join(checksInProgressName, ", "),
+			"nextPollSeconds", opts.pollInterval().Seconds(),
 		)
-		time.Sleep(time.Second * time.Duration(sleepTimeSeconds))
+		actions.EndGroup()
+		time.Sleep(opts.pollInterval())
+	}
+}
+
+// timedOut gives up on every unfinished check because the overall timeout was reached.
+func (s Service) timedOut(ctx context.Context, owner, repo, sha string, tracker statusTracker, opts WaitOptions, err error) ([]Status, error) {
+	// the policy is decided before the unfinished checks are given up on, so that running out of time isn't
+	// mistaken for them failing.
+	decided, policyErr := false, error(nil)
+	if opts.Policy != nil {
+		decided, policyErr = tracker.decide(opts.Policy)
+	}
+
+	tracker.markTimedOut(opts.Timeout)
+	s.attachSuggestions(ctx, owner, repo, sha, tracker, opts)
+	switch {
+	case decided:
+		return tracker.All(), policyErr
+	case opts.Policy != nil:
+		return tracker.All(), newTimeoutError(tracker.GetIncompleteChecks(), err)
+	case opts.Mode == ModeCollectAll:
+		return tracker.All(), tracker.collectedError(err)
+	case tracker.requiredSucceeded():
+		// only checks that can't fail the wait were still running.
+		return tracker.All(), nil
+	default:
+		return tracker.All(), newTimeoutError(RequiredStatuses(tracker.GetIncompleteChecks()), err)
+	}
+}
+
+// trackedChecks returns CheckNames along with any checks the policy names that aren't in it.
+func (opts WaitOptions) trackedChecks() []string {
+	if opts.Policy == nil {
+		return opts.CheckNames
+	}
+
+	checks := append([]string(nil), opts.CheckNames...)
+	for _, name := range opts.Policy.Checks() {
+		if !slices.Contains(checks, name) {
+			checks = append(checks, name)
+		}
+	}
+	return checks
+}
+
+// decide evaluates the policy against the checks so far. The bool is false until its outcome is certain.
+func (t statusTracker) decide(p *policy.Policy) (bool, error) {
+	switch p.Eval(t.outcome) {
+	case policy.True:
+		return true, nil
+	case policy.False:
+		clause, failed := p.FailedClause(t.outcome)
+		return true, ChecksFailedError{Checks: failed, Clause: clause}
+	default:
+		return false, nil
+	}
+}
+
+// optionalDecided reports whether none of the required checks that the policy marks optional are still being waited
+// for.
+func (t statusTracker) optionalDecided(p *policy.Policy) bool {
+	optional := p.OptionalChecks()
+	for _, status := range RequiredStatuses(t.waitingOn()) {
+		if slices.Contains(optional, status.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// outcome is how a check counts towards the policy. Checks that are still being waited for are unknown.
+func (t statusTracker) outcome(name string) policy.Value {
+	status, ok := t[name]
+	switch {
+	case !ok:
+		return policy.Unknown
+	case status.Succeeded:
+		return policy.True
+	case status.givenUp || status.MissedDeadline != "":
+		return policy.False
+	default:
+		return policy.Unknown
+	}
+}
+
+// retryOrGiveUp re-runs the failed checks that can be retried, and gives up on the rest.
+func (s Service) retryOrGiveUp(ctx context.Context, owner, repo string, tracker statusTracker, failedChecks []Status, opts WaitOptions) {
+	var retryable []Status
+	for _, status := range failedChecks {
+		if policy, ok := findRetryPolicy(opts.RetryPolicies, status.Name); ok && status.Retries < policy.MaxRetries {
+			retryable = append(retryable, status)
+		} else {
+			tracker.giveUp(ctx, status.Name, opts)
+		}
+	}
+
+	if len(retryable) == 0 {
+		return
+	}
+
+	retried, err := s.retryFailedChecks(ctx, owner, repo, tracker, retryable, opts)
+	if err != nil {
+		opts.logger().WarnContext(ctx, "failed to retry checks", "error", err)
+	}
+	if !retried {
+		for _, status := range retryable {
+			tracker.giveUp(ctx, status.Name, opts)
+		}
+	}
+}
+
+// giveUp stops waiting for a check that has failed for good or missed a deadline.
+func (t statusTracker) giveUp(ctx context.Context, name string, opts WaitOptions) {
+	status := t[name]
+	status.givenUp = true
+	t[name] = status
+
+	opts.logger().WarnContext(ctx, "giving up on check", "check", name, "state", status.State(), "missedDeadline", status.MissedDeadline)
+	if opts.OnCheckGivenUp != nil {
+		opts.OnCheckGivenUp(ctx, status)
+	}
+}
+
+// collectedError says why a collect-all wait didn't succeed once it's over. Failures come first, because a check
+// that failed is a surer sign of a problem than one that ran out of time.
+func (t statusTracker) collectedError(timeoutErr error) error {
+	if failed := RequiredStatuses(t.GetFailedChecks()); len(failed) > 0 {
+		return ChecksFailedError{Checks: StatusNames(failed)}
+	}
+
+	if incomplete := RequiredStatuses(t.GetIncompleteChecks()); timeoutErr != nil && len(incomplete) > 0 {
+		return newTimeoutError(incomplete, timeoutErr)
+	}
+
+	if missed := RequiredStatuses(MissedDeadlineStatuses(t.All())); len(missed) > 0 {
+		return newTimeoutError(missed, deadlineError{missed: missed})
 	}
+	return nil
+}
+
+// CommitInfo describes the commit that the statuses are being tracked for.
+type CommitInfo struct {
+	SHA                string     `json:"sha"`
+	Author             CommitUser `json:"author"`
+	Committer          CommitUser `json:"committer"`
+	Message            string     `json:"message"`
+	Subject            string     `json:"subject"`
+	AuthoredAt         time.Time  `json:"authoredAt"`
+	CommittedAt        time.Time  `json:"committedAt"`
+	Parents            []string   `json:"parents"`
+	Verified           bool       `json:"verified"`
+	VerificationReason string     `json:"verificationReason"`
+	HTMLURL            string     `json:"htmlUrl"`
 }
 
-func (s Service) GetCommitInfo(ctx context.Context, owner, repo, sha string) (string, string, string) {
+// CommitUser is the git identity combined with the GitHub account (if any) of a commit author or committer.
+type CommitUser struct {
+	Name      string `json:"name"`
+	Login     string `json:"login"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatarUrl"`
+}
+
+// CommitURL returns the GitHub URL for a commit without making any API calls.
+func CommitURL(owner, repo, sha string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/commit/%s", owner, repo, sha)
+}
+
+func (s Service) GetCommitInfo(ctx context.Context, owner, repo, sha string) (CommitInfo, error) {
 	c, _, err := s.client.Repositories.GetCommit(ctx, owner, repo, sha, nil)
 	if err != nil {
-		return "failed to retrieve", "failed to retrieve", fmt.Sprintf("https://github.com/%s/%s/commit/%s", owner, repo, sha)
+		return CommitInfo{}, fmt.Errorf("failed to get commit %s - %w", sha, err)
 	}
 
-	trimmedCommitMessage := c.Commit.GetMessage()
-	if len(trimmedCommitMessage) > 45 {
-		trimmedCommitMessage = trimmedCommitMessage[:45] + "..."
+	message := c.Commit.GetMessage()
+	subject, _, _ := strings.Cut(message, "\n")
+
+	var parents []string
+	for _, parent := range c.Parents {
+		parents = append(parents, parent.GetSHA())
 	}
 
-	return c.Commit.GetAuthor().GetName(), trimmedCommitMessage, c.GetHTMLURL()
+	return CommitInfo{
+		SHA: c.GetSHA(),
+		Author: CommitUser{
+			Name:      c.Commit.GetAuthor().GetName(),
+			Login:     c.GetAuthor().GetLogin(),
+			Email:     c.Commit.GetAuthor().GetEmail(),
+			AvatarURL: c.GetAuthor().GetAvatarURL(),
+		},
+		Committer: CommitUser{
+			Name:      c.Commit.GetCommitter().GetName(),
+			Login:     c.GetCommitter().GetLogin(),
+			Email:     c.Commit.GetCommitter().GetEmail(),
+			AvatarURL: c.GetCommitter().GetAvatarURL(),
+		},
+		Message:            message,
+		Subject:            strings.TrimSpace(subject),
+		AuthoredAt:         c.Commit.GetAuthor().GetDate(),
+		CommittedAt:        c.Commit.GetCommitter().GetDate(),
+		Parents:            parents,
+		Verified:           c.Commit.GetVerification().GetVerified(),
+		VerificationReason: c.Commit.GetVerification().GetReason(),
+		HTMLURL:            c.GetHTMLURL(),
+	}, nil
 }
 
 func (s Service) check(ctx context.Context, owner string, repo string, sha string, statusTracker statusTracker) error {
@@ -84,35 +436,84 @@ func (s Service) check(ctx context.Context, owner string, repo string, sha strin
 		return err
 	}
 
+	checkRuns, err := s.listCheckRuns(ctx, owner, repo, sha)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
 	for name, status := range statusTracker {
-		if status.Finished {
+		if status.Finished || status.MissedDeadline != "" {
 			continue
 		}
 
 		for _, gitStatus := range combinedStatus.Statuses {
-			if gitStatus.GetContext() == name {
-				stat := statusTracker[name]
-				stat.Finished = true
-				stat.Url = gitStatus.GetTargetURL()
+			if gitStatus.GetContext() == name && gitStatus.GetID() != status.retriedID {
+				// each state of a commit status is a new status, so when it was created is when it changed state.
+				at := timeOr(gitStatus.GetCreatedAt(), now)
 				switch gitStatus.GetState() {
 				case "success":
-					stat.Succeeded = true
-					statusTracker[name] = stat
-				case "error":
-					stat.Succeeded = false
-					statusTracker[name] = stat
-				case "failure":
-					stat.Succeeded = false
-					statusTracker[name] = stat
+					status = status.observe(at, at, gitStatus.GetID(), gitStatus.GetTargetURL(), true, true)
+				case "error", "failure":
+					status = status.observe(at, at, gitStatus.GetID(), gitStatus.GetTargetURL(), true, false)
+				default:
+					status = status.observe(at, at, gitStatus.GetID(), gitStatus.GetTargetURL(), false, false)
 				}
+				status.Description = gitStatus.GetDescription()
+				break
+			}
+		}
+
+		for _, checkRun := range checkRuns {
+			if checkRun.GetName() != name || checkRun.GetID() == status.retriedID {
+				continue
+			}
+
+			status.CheckRunID = checkRun.GetID()
+			status.Description = checkRun.GetOutput().GetTitle()
+			startedAt := timeOr(checkRun.GetStartedAt().Time, now)
+			if checkRun.GetStatus() != "completed" {
+				status = status.observe(startedAt, startedAt, checkRun.GetID(), checkRun.GetHTMLURL(), false, false)
 				break
 			}
+
+			completedAt := timeOr(checkRun.GetCompletedAt().Time, now)
+			switch checkRun.GetConclusion() {
+			case "success", "neutral", "skipped":
+				status = status.observe(startedAt, completedAt, checkRun.GetID(), checkRun.GetHTMLURL(), true, true)
+			default:
+				status = status.observe(startedAt, completedAt, checkRun.GetID(), checkRun.GetHTMLURL(), true, false)
+			}
+			break
+		}
+
+		if status.FirstSeenAt.IsZero() && status.State() != StateMissing {
+			status.FirstSeenAt = now
 		}
+		statusTracker[name] = status
 	}
 
 	return nil
 }
 
+func (s Service) listCheckRuns(ctx context.Context, owner, repo, sha string) ([]*github.CheckRun, error) {
+	opts := &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+
+	var checkRuns []*github.CheckRun
+	for {
+		result, res, err := s.client.Checks.ListCheckRunsForRef(ctx, owner, repo, sha, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		checkRuns = append(checkRuns, result.CheckRuns...)
+		if res.NextPage == 0 {
+			return checkRuns, nil
+		}
+		opts.Page = res.NextPage
+	}
+}
+
 type statusTracker map[string]Status
 
 type Status struct {
@@ -120,12 +521,134 @@ type Status struct {
 	Succeeded bool
 	Finished  bool
 	Url       string
+	// Description is the description of a commit status, or the output title of a check run.
+	Description string
+	// StartedAt is when the check started, and CompletedAt is when it finished, both according to GitHub where it
+	// says. FirstSeenAt is when the check was first reported to this tool.
+	StartedAt   time.Time
+	CompletedAt time.Time
+	FirstSeenAt time.Time
+	// Transitions records every change in State, in the order they were observed.
+	Transitions []Transition
+	// CheckRunID is only populated for statuses reported as check runs rather than commit statuses.
+	CheckRunID int64
+
+	// FailedStep and LogExcerpt are only populated for failed statuses backed by GitHub Actions jobs.
+	FailedStep string
+	LogExcerpt string
+	// FailedTests is only populated for failed statuses whose workflow run uploaded test reports.
+	FailedTests []testreport.Failure
+	// Annotations is only populated for failed check runs that have annotations.
+	Annotations []Annotation
+	// Retries is the number of times the check was re-run after failing.
+	Retries int
+	// Suggestions are the names of reported checks that look like this one, if it was never reported itself.
+	Suggestions []string
+	// MissedDeadline is one of DeadlineAppear, DeadlineFinish or DeadlineWait if the check was given up on because it
+	// took too long, and MissedDeadlineAfter is how long it had.
+	MissedDeadline      string
+	MissedDeadlineAfter time.Duration
+	// ExpectedDuration is how long the check usually takes, if that's been configured.
+	ExpectedDuration time.Duration
+	// Severity is SeverityRequired, SeverityWarn or SeverityInfo.
+	Severity string
+	// Stage is the name of the stage the check belongs to, if any. Skipped is set if it wasn't waited for because an
+	// earlier stage failed.
+	Stage   string
+	Skipped bool
+
+	// observedID is the ID of the commit status or check run that the current state came from. retriedID is the ID of
+	// the last one that was re-run, so that its result is ignored until the re-run reports back.
+	observedID    int64
+	retriedID     int64
+	overranLogged bool
+	// attemptStartedAt is when the current attempt started, which is StartedAt until the check is re-run.
+	attemptStartedAt time.Time
+	// givenUp is set in collect-all mode once the check has failed for good or missed a deadline.
+	givenUp bool
 }
 
 func newStatus(name string) Status {
 	return Status{Name: name}
 }
 
+// Transition is a change in the State of a check.
+type Transition struct {
+	State string    `json:"state"`
+	At    time.Time `json:"at"`
+}
+
+// observe records the state of a check as reported by GitHub. at is when it changed to that state, and startedAt is
+// when it started, which is only used if it hasn't already been seen, or if this is the first time the current attempt
+// has been seen.
+func (status Status) observe(startedAt, at time.Time, id int64, url string, finished, succeeded bool) Status {
+	previousState := status.State()
+
+	if status.StartedAt.IsZero() {
+		status.StartedAt = startedAt
+	}
+	if status.attemptStartedAt.IsZero() {
+		status.attemptStartedAt = startedAt
+	}
+	if finished {
+		status.CompletedAt = at
+	}
+
+	status.observedID = id
+	status.Url = url
+	status.Finished = finished
+	status.Succeeded = succeeded
+
+	if state := status.State(); state != previousState || len(status.Transitions) == 0 {
+		status.Transitions = append(status.Transitions, Transition{State: state, At: at})
+	}
+	return status
+}
+
+func timeOr(t, fallback time.Time) time.Time {
+	if t.IsZero() {
+		return fallback
+	}
+	return t
+}
+
+const (
+	StateSuccess = "success"
+	StateFailure = "failure"
+	StatePending = "pending"
+	StateMissing = "missing"
+	// StateRetrying only appears in Transitions, to mark where a failed check was re-run.
+	StateRetrying = "retrying"
+)
+
+// State is one of success, failure, pending (seen but not finished) or missing (never seen).
+func (status Status) State() string {
+	switch {
+	case status.Finished && status.Succeeded:
+		return StateSuccess
+	case status.Finished:
+		return StateFailure
+	case status.Skipped:
+		return StateSkipped
+	case !status.StartedAt.IsZero():
+		return StatePending
+	default:
+		return StateMissing
+	}
+}
+
+// Duration is how long the check was observed running for, up until now if it hasn't finished yet.
+func (status Status) Duration() time.Duration {
+	switch {
+	case status.StartedAt.IsZero():
+		return 0
+	case status.CompletedAt.IsZero():
+		return time.Since(status.StartedAt)
+	default:
+		return status.CompletedAt.Sub(status.StartedAt)
+	}
+}
+
 func newStatusTracker(checkNames []string) statusTracker {
 	tracker := make(statusTracker)
 	for _, name := range checkNames {
@@ -135,8 +658,57 @@ func newStatusTracker(checkNames []string) statusTracker {
 }
 
 func (t statusTracker) GetFailedChecks() []Status {
-	var failedChecks []Status
+	return FailedStatuses(t.All())
+}
+
+func (t statusTracker) GetIncompleteChecks() []Status {
+	return IncompleteStatuses(t.All())
+}
+
+// newlyFailedChecks returns the failed checks that haven't been given up on yet.
+func (t statusTracker) newlyFailedChecks() []Status {
+	var failed []Status
+	for _, status := range t.GetFailedChecks() {
+		if !status.givenUp {
+			failed = append(failed, status)
+		}
+	}
+	return failed
+}
+
+// waitingOn returns the checks that are still being waited for.
+func (t statusTracker) waitingOn() []Status {
+	var waiting []Status
+	for _, status := range t.GetIncompleteChecks() {
+		if !status.givenUp && status.MissedDeadline == "" {
+			waiting = append(waiting, status)
+		}
+	}
+	return waiting
+}
+
+// All returns every tracked status, ordered by name.
+func (t statusTracker) All() []Status {
+	statuses := make([]Status, 0, len(t))
 	for _, status := range t {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// StatusNames returns the names of the statuses, in the same order.
+func StatusNames(statuses []Status) []string {
+	names := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		names = append(names, status.Name)
+	}
+	return names
+}
+
+func FailedStatuses(statuses []Status) []Status {
+	var failedChecks []Status
+	for _, status := range statuses {
 		if !status.Succeeded && status.Finished {
 			failedChecks = append(failedChecks, status)
 		}
@@ -144,9 +716,9 @@ func (t statusTracker) GetFailedChecks() []Status {
 	return failedChecks
 }
 
-func (t statusTracker) GetIncompleteChecks() []Status {
+func IncompleteStatuses(statuses []Status) []Status {
 	var incompleteChecks []Status
-	for _, status := range t {
+	for _, status := range statuses {
 		if !status.Succeeded && !status.Finished {
 			incompleteChecks = append(incompleteChecks, status)
 		}
@@ -154,12 +726,13 @@ func (t statusTracker) GetIncompleteChecks() []Status {
 	return incompleteChecks
 }
 
-func (t statusTracker) AllCompletedSuccessfully() bool {
-	for _, status := range t {
+// UnsuccessfulStatuses returns every status that didn't succeed, whether it failed or never finished.
+func UnsuccessfulStatuses(statuses []Status) []Status {
+	var unsuccessful []Status
+	for _, status := range statuses {
 		if !status.Succeeded {
-			return false
+			unsuccessful = append(unsuccessful, status)
 		}
 	}
-
-	return true
+	return unsuccessful
 }
@@ -0,0 +1,237 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v42/github"
+)
+
+// fakeGitHub serves the check runs for commit sha of o/r from a script with one entry per poll. The last entry is
+// repeated once the script runs out. Workflow runs that are re-run are recorded.
+type fakeGitHub struct {
+	t     *testing.T
+	polls [][]*github.CheckRun
+
+	mu     sync.Mutex
+	poll   int
+	reruns []string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/o/r/commits/sha/status":
+		f.respond(w, github.CombinedStatus{})
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/o/r/commits/sha/check-runs":
+		checkRuns := f.polls[min(f.poll, len(f.polls)-1)]
+		f.poll++
+		f.respond(w, github.ListCheckRunsResults{Total: github.Int(len(checkRuns)), CheckRuns: checkRuns})
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/rerun-failed-jobs"):
+		f.reruns = append(f.reruns, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeGitHub) respond(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		f.t.Error(err)
+	}
+}
+
+func newTestService(t *testing.T, handler http.Handler) Service {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL = baseURL
+	return Service{client: client, transport: &countingTransport{base: http.DefaultTransport}}
+}
+
+// checkRun is a check run of an Actions job in workflow run 5 that started an hour ago. Conclusion is empty while it's
+// still running.
+func checkRun(id int64, name, conclusion string) *github.CheckRun {
+	run := &github.CheckRun{
+		ID:        github.Int64(id),
+		Name:      github.String(name),
+		Status:    github.String("in_progress"),
+		HTMLURL:   github.String("https://github.com/o/r/actions/runs/5/job/" + strconv.FormatInt(id, 10)),
+		StartedAt: &github.Timestamp{Time: time.Now().Add(-time.Hour)},
+	}
+	if conclusion != "" {
+		run.Status = github.String("completed")
+		run.Conclusion = github.String(conclusion)
+		run.CompletedAt = &github.Timestamp{Time: time.Now()}
+	}
+	return run
+}
+
+func TestWaitForChecksToSucceed(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  WaitOptions
+		polls [][]*github.CheckRun
+		// wantErr is part of the error expected, or empty if the wait should succeed.
+		wantErr    string
+		wantStates map[string]string
+		wantReruns int
+	}{
+		{
+			name: "fail-fast stops at the first required failure",
+			opts: WaitOptions{CheckNames: []string{"build", "test"}},
+			polls: [][]*github.CheckRun{
+				{checkRun(1, "build", "failure"), checkRun(2, "test", "")},
+				{checkRun(1, "build", "failure"), checkRun(2, "test", "failure")},
+			},
+			wantErr:    "one or more checks failed - build",
+			wantStates: map[string]string{"build": StateFailure, "test": StatePending},
+		},
+		{
+			name: "collect-all waits for every check",
+			opts: WaitOptions{CheckNames: []string{"build", "lint", "test"}, Mode: ModeCollectAll},
+			polls: [][]*github.CheckRun{
+				{checkRun(1, "build", "failure"), checkRun(2, "test", ""), checkRun(3, "lint", "")},
+				{checkRun(1, "build", "failure"), checkRun(2, "test", "failure"), checkRun(3, "lint", "success")},
+			},
+			wantErr:    "one or more checks failed - build, test",
+			wantStates: map[string]string{"build": StateFailure, "lint": StateSuccess, "test": StateFailure},
+		},
+		{
+			name: "a check that misses its appear deadline is given up on",
+			opts: WaitOptions{
+				CheckNames:    []string{"build", "deploy"},
+				CheckTimeouts: []CheckTimeouts{{Pattern: "deploy", AppearWithin: time.Millisecond}},
+			},
+			polls:      [][]*github.CheckRun{{checkRun(1, "build", "")}},
+			wantErr:    "deploy didn't appear within 1ms",
+			wantStates: map[string]string{"build": StatePending, "deploy": StateMissing},
+		},
+		{
+			name: "a check that misses its finish deadline is given up on",
+			opts: WaitOptions{
+				CheckNames:    []string{"build"},
+				CheckTimeouts: []CheckTimeouts{{Pattern: "build", FinishWithin: time.Minute}},
+			},
+			polls:      [][]*github.CheckRun{{checkRun(1, "build", "")}},
+			wantErr:    "build didn't finish within 1m0s",
+			wantStates: map[string]string{"build": StatePending},
+		},
+		{
+			name: "a failed stage skips the stages after it",
+			opts: WaitOptions{
+				CheckNames: []string{"build", "e2e"},
+				Mode:       ModeCollectAll,
+				Stages:     []Stage{{Name: "build", Checks: []string{"build"}}, {Name: "test", Checks: []string{"e2e"}}},
+			},
+			polls:      [][]*github.CheckRun{{checkRun(1, "build", "failure")}},
+			wantErr:    "failed at stage build (1/2) - one or more checks failed - build",
+			wantStates: map[string]string{"build": StateFailure, "e2e": StateSkipped},
+		},
+		{
+			name: "a failed check is retried and its old result ignored",
+			opts: WaitOptions{
+				CheckNames:    []string{"e2e"},
+				RetryPolicies: []RetryPolicy{{Pattern: "e2e", MaxRetries: 1}},
+			},
+			polls: [][]*github.CheckRun{
+				{checkRun(1, "e2e", "failure")},
+				{checkRun(1, "e2e", "failure")},
+				{checkRun(2, "e2e", "success")},
+			},
+			wantStates: map[string]string{"e2e": StateSuccess},
+			wantReruns: 1,
+		},
+		{
+			name: "a check that fails again once it's out of retries fails the wait",
+			opts: WaitOptions{
+				CheckNames:    []string{"e2e"},
+				RetryPolicies: []RetryPolicy{{Pattern: "e2e", MaxRetries: 1}},
+			},
+			polls: [][]*github.CheckRun{
+				{checkRun(1, "e2e", "failure")},
+				{checkRun(2, "e2e", "failure")},
+			},
+			wantErr:    "one or more checks failed - e2e",
+			wantStates: map[string]string{"e2e": StateFailure},
+			wantReruns: 1,
+		},
+		{
+			name: "warn checks are waited for but can't fail the wait",
+			opts: WaitOptions{
+				CheckNames: []string{"build", "lint"},
+				Severities: []CheckSeverity{{Pattern: "lint", Severity: SeverityWarn}},
+			},
+			polls: [][]*github.CheckRun{
+				{checkRun(1, "build", "success"), checkRun(2, "lint", "")},
+				{checkRun(1, "build", "success"), checkRun(2, "lint", "failure")},
+			},
+			wantStates: map[string]string{"build": StateSuccess, "lint": StateFailure},
+		},
+		{
+			name: "running out of time on a warn check isn't a failure",
+			opts: WaitOptions{
+				CheckNames: []string{"build", "lint"},
+				Severities: []CheckSeverity{{Pattern: "lint", Severity: SeverityWarn}},
+				Timeout:    50 * time.Millisecond,
+			},
+			polls:      [][]*github.CheckRun{{checkRun(1, "build", "success"), checkRun(2, "lint", "")}},
+			wantStates: map[string]string{"build": StateSuccess, "lint": StatePending},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeGitHub{t: t, polls: tt.polls}
+			service := newTestService(t, fake)
+
+			opts := tt.opts
+			opts.PollInterval = time.Millisecond
+			opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+			if opts.Timeout == 0 {
+				opts.Timeout = 5 * time.Second
+			}
+
+			statuses, err := service.WaitForChecksToSucceed(context.Background(), "o", "r", "sha", opts)
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+
+			states := make(map[string]string)
+			for _, status := range statuses {
+				states[status.Name] = status.State()
+			}
+			if !reflect.DeepEqual(states, tt.wantStates) {
+				t.Errorf("expected states %v, got %v", tt.wantStates, states)
+			}
+
+			if len(fake.reruns) != tt.wantReruns {
+				t.Errorf("expected %d re-runs, got %v", tt.wantReruns, fake.reruns)
+			}
+		})
+	}
+}
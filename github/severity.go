@@ -0,0 +1,97 @@
+package github
+
+import "path"
+
+// The severities a check can have. Only required checks can fail the wait. Warn checks are still waited for, so that
+// one that fails can be alerted about without failing the wait, but running out of time on one isn't a failure. Info
+// checks aren't waited for once the others are done, and are reported in whatever state they're in by then.
+const (
+	SeverityRequired = "required"
+	SeverityWarn     = "warn"
+	SeverityInfo     = "info"
+)
+
+// CheckSeverity gives every check whose name matches Pattern, in path.Match syntax, a severity.
+type CheckSeverity struct {
+	Pattern  string
+	Severity string
+}
+
+// FindSeverity returns the severity of the first of severities that matches the check, or SeverityRequired if none do.
+func FindSeverity(severities []CheckSeverity, checkName string) string {
+	for _, severity := range severities {
+		if matched, _ := path.Match(severity.Pattern, checkName); matched {
+			return severity.Severity
+		}
+	}
+	return SeverityRequired
+}
+
+func (t statusTracker) setSeverities(severities []CheckSeverity) {
+	for name, status := range t {
+		status.Severity = FindSeverity(severities, name)
+		t[name] = status
+	}
+}
+
+// Required reports whether the check failing fails the wait. Statuses that weren't given a severity are required.
+func (status Status) Required() bool {
+	return status.Severity == "" || status.Severity == SeverityRequired
+}
+
+// requiredSucceeded reports whether every required check has succeeded.
+func (t statusTracker) requiredSucceeded() bool {
+	for _, status := range t {
+		if status.Required() && !status.Succeeded {
+			return false
+		}
+	}
+	return true
+}
+
+// requiredDecided reports whether none of the required checks are still being waited for.
+func (t statusTracker) requiredDecided() bool {
+	return len(RequiredStatuses(t.waitingOn())) == 0
+}
+
+// warnDecided reports whether none of the warn checks are still being waited for.
+func (t statusTracker) warnDecided() bool {
+	for _, status := range t.waitingOn() {
+		if status.Severity == SeverityWarn {
+			return false
+		}
+	}
+	return true
+}
+
+func splitRequired(statuses []Status) (required, nonBlocking []Status) {
+	for _, status := range statuses {
+		if status.Required() {
+			required = append(required, status)
+		} else {
+			nonBlocking = append(nonBlocking, status)
+		}
+	}
+	return required, nonBlocking
+}
+
+// RequiredStatuses returns the statuses of the required checks.
+func RequiredStatuses(statuses []Status) []Status {
+	required, _ := splitRequired(statuses)
+	return required
+}
+
+// NonBlockingFailures returns the checks with the given severity that failed or missed one of their own deadlines.
+// Still running when the wait timed out doesn't count, because only required checks can time it out.
+func NonBlockingFailures(statuses []Status, severity string) []Status {
+	var failed []Status
+	for _, status := range statuses {
+		if status.Severity != severity || status.Succeeded {
+			continue
+		}
+		if status.Finished || status.MissedDeadline == DeadlineAppear || status.MissedDeadline == DeadlineFinish {
+			failed = append(failed, status)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,93 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v42/github"
+)
+
+// Snapshot returns the current state of the given checks on a commit, without waiting for any of them.
+func (s Service) Snapshot(ctx context.Context, owner, repo, sha string, checkNames []string) ([]Status, error) {
+	tracker := newStatusTracker(checkNames)
+	if err := s.check(ctx, owner, repo, sha, tracker); err != nil {
+		return nil, newAPIError(fmt.Errorf("failed to get statuses for commit - %w", err))
+	}
+	return tracker.All(), nil
+}
+
+// ListContexts returns the names of every commit status and check run reported for a commit, sorted and without
+// duplicates. They're the names that can be waited for.
+func (s Service) ListContexts(ctx context.Context, owner, repo, sha string) ([]string, error) {
+	combinedStatus, _, err := s.client.Repositories.GetCombinedStatus(ctx, owner, repo, sha, nil)
+	if err != nil {
+		return nil, newAPIError(fmt.Errorf("failed to get statuses for commit - %w", err))
+	}
+
+	checkRuns, err := s.listCheckRuns(ctx, owner, repo, sha)
+	if err != nil {
+		return nil, newAPIError(fmt.Errorf("failed to get check runs for commit - %w", err))
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, status := range combinedStatus.Statuses {
+		add(status.GetContext())
+	}
+	for _, checkRun := range checkRuns {
+		add(checkRun.GetName())
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s Service) DefaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	repository, _, err := s.client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return "", newAPIError(fmt.Errorf("failed to get repository - %w", err))
+	}
+	return repository.GetDefaultBranch(), nil
+}
+
+// BranchCommit is a commit that was made on a branch.
+type BranchCommit struct {
+	SHA         string
+	CommittedAt time.Time
+}
+
+// ListBranchCommits returns the commits made on a branch between since and until, oldest first.
+func (s Service) ListBranchCommits(ctx context.Context, owner, repo, branch string, since, until time.Time) ([]BranchCommit, error) {
+	opts := &github.CommitsListOptions{SHA: branch, Since: since, Until: until, ListOptions: github.ListOptions{PerPage: 100}}
+
+	var commits []BranchCommit
+	for {
+		page, res, err := s.client.Repositories.ListCommits(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, newAPIError(fmt.Errorf("failed to list commits on %s - %w", branch, err))
+		}
+
+		for _, commit := range page {
+			commits = append(commits, BranchCommit{SHA: commit.GetSHA(), CommittedAt: commit.GetCommit().GetCommitter().GetDate()})
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	// the API lists the newest commits first.
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return commits, nil
+}
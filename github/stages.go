@@ -0,0 +1,187 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+)
+
+// StateSkipped is the state of a check that wasn't waited for because an earlier stage failed.
+const StateSkipped = "skipped"
+
+// Stage is a named group of checks that runs after the stages before it. Checks are check names in path.Match
+// syntax, and a check belongs to the first stage that matches it.
+type Stage struct {
+	Name   string
+	Checks []string
+}
+
+func (stage Stage) matches(checkName string) bool {
+	for _, pattern := range stage.Checks {
+		if matched, _ := path.Match(pattern, checkName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func findStage(stages []Stage, checkName string) (Stage, bool) {
+	for _, stage := range stages {
+		if stage.matches(checkName) {
+			return stage, true
+		}
+	}
+	return Stage{}, false
+}
+
+func (t statusTracker) setStages(stages []Stage) {
+	for name, status := range t {
+		if stage, ok := findStage(stages, name); ok {
+			status.Stage = stage.Name
+			t[name] = status
+		}
+	}
+}
+
+// skipLaterStages stops waiting for the checks in every stage after the first one that failed, because a pipeline
+// doesn't run them once an earlier stage has failed.
+func (t statusTracker) skipLaterStages(ctx context.Context, opts WaitOptions) {
+	results := StageResults(t.All(), opts.Stages)
+	failed, ok := failedStage(results)
+	if !ok {
+		return
+	}
+
+	for _, result := range results[failed+1:] {
+		for _, name := range result.Checks {
+			status := t[name]
+			if status.Finished || status.givenUp {
+				continue
+			}
+
+			status.Skipped, status.givenUp = true, true
+			t[name] = status
+			opts.logger().InfoContext(ctx, "not waiting for check because an earlier stage failed", "check", name, "stage", result.Name, "failedStage", results[failed].Name)
+		}
+	}
+}
+
+// StageResult is how far a stage got.
+type StageResult struct {
+	Name string `json:"name"`
+	// State is StateSuccess once every required check in the stage has succeeded, StateFailure if any of them failed
+	// or missed one of its own deadlines, StateSkipped if an earlier stage failed, StateMissing if none of its checks
+	// have been reported yet, and StatePending otherwise.
+	State  string   `json:"state"`
+	Checks []string `json:"checks"`
+}
+
+// StageResults works out the state of every stage from the statuses of its checks, in the order of the stages.
+func StageResults(statuses []Status, stages []Stage) []StageResult {
+	results := make([]StageResult, 0, len(stages))
+	byStage := make(map[string][]Status)
+	for _, status := range statuses {
+		if status.Stage != "" {
+			byStage[status.Stage] = append(byStage[status.Stage], status)
+		}
+	}
+
+	for _, stage := range stages {
+		result := StageResult{Name: stage.Name, State: stageState(byStage[stage.Name])}
+		for _, status := range byStage[stage.Name] {
+			result.Checks = append(result.Checks, status.Name)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func stageState(statuses []Status) string {
+	succeeded, skipped, seen := true, true, false
+	for _, status := range statuses {
+		if !status.Skipped {
+			skipped = false
+		}
+		if status.State() != StateMissing {
+			seen = true
+		}
+		if !status.Required() {
+			continue
+		}
+
+		if !status.Succeeded {
+			succeeded = false
+		}
+		if (status.Finished && !status.Succeeded) || status.MissedDeadline == DeadlineAppear || status.MissedDeadline == DeadlineFinish {
+			return StateFailure
+		}
+	}
+
+	switch {
+	case len(statuses) > 0 && skipped:
+		return StateSkipped
+	case succeeded:
+		return StateSuccess
+	case seen:
+		return StatePending
+	default:
+		return StateMissing
+	}
+}
+
+func failedStage(results []StageResult) (int, bool) {
+	for i, result := range results {
+		if result.State == StateFailure {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// StageError says which stage the wait stopped at, which is the first one that failed, or the first one that hadn't
+// succeeded if none did.
+type StageError struct {
+	Stage    string
+	Position int
+	Total    int
+	Failed   bool
+	Err      error
+}
+
+func (e StageError) Error() string {
+	verb := "stopped"
+	if e.Failed {
+		verb = "failed"
+	}
+	return fmt.Sprintf("%s at stage %s (%d/%d) - %s", verb, e.Stage, e.Position, e.Total, e.Err)
+}
+
+func (e StageError) Unwrap() error {
+	return e.Err
+}
+
+// withStage wraps an error about the checks in a StageError, if there are stages and one of them hadn't succeeded.
+// Other errors, like those from the API, have nothing to do with the stages.
+func withStage(err error, statuses []Status, stages []Stage) error {
+	var (
+		checksFailedErr  ChecksFailedError
+		timedOutErr      TimedOutError
+		checksMissingErr ChecksMissingError
+	)
+	if len(stages) == 0 || !(errors.As(err, &checksFailedErr) || errors.As(err, &timedOutErr) || errors.As(err, &checksMissingErr)) {
+		return err
+	}
+
+	results := StageResults(statuses, stages)
+	if i, ok := failedStage(results); ok {
+		return StageError{Stage: results[i].Name, Position: i + 1, Total: len(results), Failed: true, Err: err}
+	}
+
+	for i, result := range results {
+		if result.State != StateSuccess {
+			return StageError{Stage: result.Name, Position: i + 1, Total: len(results), Err: err}
+		}
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package github
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/tracing"
+)
+
+// APIStats counts the requests made to the GitHub API.
+type APIStats struct {
+	Requests int `json:"requests"`
+	// Errors counts responses with a status code of 400 or above, as well as requests that failed to get a response.
+	Errors int `json:"errors"`
+	// RateLimitRemaining is the number of requests left in the current rate limit window, as of the last response.
+	RateLimitRemaining int `json:"rateLimitRemaining"`
+}
+
+// countingTransport records APIStats for every request that goes through it.
+type countingTransport struct {
+	base http.RoundTripper
+
+	mu    sync.Mutex
+	stats APIStats
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	startedAt := time.Now()
+	res, err := t.base.RoundTrip(req)
+
+	attributes := map[string]any{
+		"http.method":      req.Method,
+		"http.path":        req.URL.Path,
+		"http.duration_ms": time.Since(startedAt).Milliseconds(),
+	}
+	if res != nil {
+		attributes["http.status_code"] = res.StatusCode
+	}
+	tracing.SpanFromContext(req.Context()).AddEvent("github.api.request", startedAt, attributes)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stats.Requests++
+	if err != nil || res.StatusCode >= 400 {
+		t.stats.Errors++
+	}
+
+	if res != nil {
+		if remaining, convErr := strconv.Atoi(res.Header.Get("X-RateLimit-Remaining")); convErr == nil {
+			t.stats.RateLimitRemaining = remaining
+		}
+	}
+
+	return res, err
+}
+
+func (t *countingTransport) Stats() APIStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
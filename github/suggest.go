@@ -0,0 +1,125 @@
+package github
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxSuggestions is how many similar names are suggested for a check that was never reported.
+const maxSuggestions = 3
+
+// attachSuggestions suggests the names that the checks which were never reported might have been meant to be, from
+// the statuses and check runs that were reported for the commit. It's best effort, because it only makes the error
+// more helpful.
+func (s Service) attachSuggestions(ctx context.Context, owner, repo, sha string, tracker statusTracker, opts WaitOptions) {
+	var missing []string
+	for name, status := range tracker {
+		if status.State() == StateMissing {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	// the wait's context has usually expired by now, but the suggestions are still worth a quick request.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	contexts, err := s.ListContexts(ctx, owner, repo, sha)
+	if err != nil {
+		opts.logger().WarnContext(ctx, "failed to list the checks reported for the commit", "error", err)
+		return
+	}
+
+	for _, name := range missing {
+		status := tracker[name]
+		status.Suggestions = suggestNames(name, contexts)
+		tracker[name] = status
+	}
+}
+
+// suggestNames returns up to maxSuggestions of the candidates that look like misspellings of name, closest first.
+func suggestNames(name string, candidates []string) []string {
+	type scored struct {
+		name     string
+		distance int
+	}
+
+	lowerName := strings.ToLower(name)
+	maxDistance := len([]rune(name)) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	var matches []scored
+	for _, candidate := range candidates {
+		lowerCandidate := strings.ToLower(candidate)
+		if candidate == name {
+			continue
+		}
+
+		distance := min(levenshtein(lowerName, lowerCandidate), levenshtein(lowerName, baseName(lowerCandidate)))
+		if distance <= maxDistance || strings.Contains(lowerCandidate, lowerName) || strings.Contains(lowerName, lowerCandidate) {
+			matches = append(matches, scored{name: candidate, distance: distance})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var suggestions []string
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, matches[i].name)
+	}
+	return suggestions
+}
+
+// baseName strips the matrix values or job name that GitHub Actions appends to a check name, e.g "test (ubuntu)" and
+// "ci / test" are both based on "test" and "ci".
+func baseName(name string) string {
+	if i := strings.Index(name, " ("); i > 0 {
+		name = name[:i]
+	}
+	if i := strings.Index(name, " / "); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// levenshtein is the number of single character insertions, deletions and substitutions it takes to turn a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// DidYouMean formats the suggestions for a check that was never reported, or is empty if there aren't any.
+func (status Status) DidYouMean() string {
+	if len(status.Suggestions) == 0 {
+		return ""
+	}
+	return "did you mean " + strings.Join(status.Suggestions, " or ") + "?"
+}
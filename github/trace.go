@@ -0,0 +1,55 @@
+package github
+
+import (
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/tracing"
+)
+
+// traceChecks ends the root span of a wait and adds a child span for each tracked check, covering the time from
+// when it started, according to GitHub, to when it finished. Checks that never started get an empty span at the end.
+func traceChecks(tracer *tracing.Tracer, root *tracing.Span, statuses []Status, err error) {
+	if tracer == nil {
+		return
+	}
+
+	endedAt := time.Now()
+	for _, status := range statuses {
+		startedAt := status.StartedAt
+		if startedAt.IsZero() {
+			startedAt = endedAt
+		}
+
+		span := tracer.Start(status.Name, root, startedAt)
+		span.SetAttribute("check.name", status.Name)
+		span.SetAttribute("check.state", status.State())
+		span.SetAttribute("check.retries", status.Retries)
+		if status.Url != "" {
+			span.SetAttribute("check.url", status.Url)
+		}
+
+		for _, transition := range status.Transitions {
+			span.AddEvent(transition.State, transition.At, nil)
+		}
+
+		switch status.State() {
+		case StateFailure:
+			span.Fail(status.Description)
+		case StatePending:
+			span.Fail("did not complete")
+		case StateMissing:
+			span.Fail("was never reported")
+		}
+
+		if status.CompletedAt.IsZero() {
+			span.End(endedAt)
+		} else {
+			span.End(status.CompletedAt)
+		}
+	}
+
+	if err != nil {
+		root.Fail(err.Error())
+	}
+	root.End(endedAt)
+}
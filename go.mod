@@ -1,10 +1,11 @@
 module github.com/tamj0rd2/pipeline-status-action
 
-go 1.19
+go 1.21
 
 require (
 	github.com/google/go-github/v42 v42.0.0
 	golang.org/x/oauth2 v0.0.0-20220822191816-0ebed06d0094
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
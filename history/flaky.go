@@ -0,0 +1,108 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+// FlakeStat summarises how often a check has failed and then succeeded on the same commit.
+type FlakeStat struct {
+	Check string
+	// Commits is the number of commits the check ran on.
+	Commits int
+	// Flakes is the number of those commits where the check failed and then later succeeded.
+	Flakes int
+}
+
+func (f FlakeStat) Rate() float64 {
+	if f.Commits == 0 {
+		return 0
+	}
+	return float64(f.Flakes) / float64(f.Commits)
+}
+
+// FlakeStats ranks checks by flake rate, considering only outcomes recorded at or after since. Checks that have never
+// flaked are left out.
+func FlakeStats(outcomes []Outcome, since time.Time) []FlakeStat {
+	type commitCheck struct{ repository, sha, check string }
+	type commitResult struct{ failed, flaked bool }
+
+	results := make(map[commitCheck]*commitResult)
+	for _, outcome := range sortedByTime(outcomes) {
+		if outcome.RecordedAt.Before(since) {
+			continue
+		}
+
+		key := commitCheck{outcome.Repository, outcome.SHA, outcome.Check}
+		result, ok := results[key]
+		if !ok {
+			result = &commitResult{}
+			results[key] = result
+		}
+
+		if !outcome.Succeeded {
+			result.failed = true
+		} else if result.failed {
+			result.flaked = true
+		}
+	}
+
+	statsByCheck := make(map[string]*FlakeStat)
+	for key, result := range results {
+		stat, ok := statsByCheck[key.check]
+		if !ok {
+			stat = &FlakeStat{Check: key.check}
+			statsByCheck[key.check] = stat
+		}
+
+		stat.Commits++
+		if result.flaked {
+			stat.Flakes++
+		}
+	}
+
+	var stats []FlakeStat
+	for _, stat := range statsByCheck {
+		if stat.Flakes > 0 {
+			stats = append(stats, *stat)
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Rate() != stats[j].Rate() {
+			return stats[i].Rate() > stats[j].Rate()
+		}
+		return stats[i].Check < stats[j].Check
+	})
+	return stats
+}
+
+// KnownFlaky returns the names of the checks in the repository that have flaked at least once.
+func KnownFlaky(outcomes []Outcome, repository string) map[string]bool {
+	flaky := make(map[string]bool)
+	for _, stat := range FlakeStats(ForRepository(outcomes, repository), time.Time{}) {
+		flaky[stat.Check] = true
+	}
+	return flaky
+}
+
+func sortedByTime(outcomes []Outcome) []Outcome {
+	sorted := append([]Outcome(nil), outcomes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].RecordedAt.Equal(sorted[j].RecordedAt) {
+			return sorted[i].RecordedAt.Before(sorted[j].RecordedAt)
+		}
+		return sorted[i].Attempt < sorted[j].Attempt
+	})
+	return sorted
+}
+
+func ForRepository(outcomes []Outcome, repository string) []Outcome {
+	var filtered []Outcome
+	for _, outcome := range outcomes {
+		if outcome.Repository == repository {
+			filtered = append(filtered, outcome)
+		}
+	}
+	return filtered
+}
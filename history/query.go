@@ -0,0 +1,48 @@
+package history
+
+import "time"
+
+// Query narrows down runs. Empty fields match everything.
+type Query struct {
+	Repository string
+	Branch     string
+	Check      string
+	Since      time.Time
+	Until      time.Time
+}
+
+func (q Query) Matches(run Run) bool {
+	if q.Repository != "" && run.Repository != q.Repository {
+		return false
+	}
+
+	if q.Branch != "" && run.Branch != q.Branch {
+		return false
+	}
+
+	if q.Check != "" {
+		if _, ok := run.Check(q.Check); !ok {
+			return false
+		}
+	}
+
+	if !q.Since.IsZero() && run.StartedAt.Before(q.Since) {
+		return false
+	}
+
+	if !q.Until.IsZero() && !run.StartedAt.Before(q.Until) {
+		return false
+	}
+
+	return true
+}
+
+func Filter(runs []Run, query Query) []Run {
+	var filtered []Run
+	for _, run := range runs {
+		if query.Matches(run) {
+			filtered = append(filtered, run)
+		}
+	}
+	return filtered
+}
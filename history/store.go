@@ -0,0 +1,178 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Store keeps records as JSON lines in a local file. Records are only ever appended, so the file can be carried
+// between workflow runs with something like actions/cache and merged by concatenating.
+type Store struct {
+	path string
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+const (
+	// kindOutcome is also assumed for records without a kind, which were written before runs were recorded.
+	kindOutcome = "outcome"
+	kindRun     = "run"
+)
+
+// Outcome is the result of a single attempt of a check on a commit.
+type Outcome struct {
+	Kind       string    `json:"kind"`
+	Repository string    `json:"repository"`
+	SHA        string    `json:"sha"`
+	Check      string    `json:"check"`
+	Attempt    int       `json:"attempt"`
+	Succeeded  bool      `json:"succeeded"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// Run is everything that was learnt from a single run of the tool.
+type Run struct {
+	Kind          string     `json:"kind"`
+	Repository    string     `json:"repository"`
+	Branch        string     `json:"branch"`
+	SHA           string     `json:"sha"`
+	Checks        []RunCheck `json:"checks"`
+	Outcome       string     `json:"outcome"`
+	ErrorCategory string     `json:"errorCategory,omitempty"`
+	StartedAt     time.Time  `json:"startedAt"`
+	FinishedAt    time.Time  `json:"finishedAt"`
+}
+
+func (r Run) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// Check returns the named check from the run, if it was tracked.
+func (r Run) Check(name string) (RunCheck, bool) {
+	for _, check := range r.Checks {
+		if check.Name == name {
+			return check, true
+		}
+	}
+	return RunCheck{}, false
+}
+
+type RunCheck struct {
+	Name            string       `json:"name"`
+	State           string       `json:"state"`
+	Retries         int          `json:"retries"`
+	DurationSeconds float64      `json:"durationSeconds"`
+	Transitions     []Transition `json:"transitions"`
+}
+
+type Transition struct {
+	State string    `json:"state"`
+	At    time.Time `json:"at"`
+}
+
+func (s *Store) AppendOutcomes(outcomes ...Outcome) error {
+	records := make([]any, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		outcome.Kind = kindOutcome
+		records = append(records, outcome)
+	}
+	return s.append(records...)
+}
+
+func (s *Store) AppendRun(run Run) error {
+	run.Kind = kindRun
+	return s.append(run)
+}
+
+// Outcomes returns every recorded outcome in the order they were recorded. A missing file has no outcomes.
+func (s *Store) Outcomes() ([]Outcome, error) {
+	var outcomes []Outcome
+	err := s.read(func(kind string, line []byte) error {
+		if kind != kindOutcome {
+			return nil
+		}
+
+		var outcome Outcome
+		if err := json.Unmarshal(line, &outcome); err != nil {
+			return err
+		}
+		outcomes = append(outcomes, outcome)
+		return nil
+	})
+	return outcomes, err
+}
+
+// Runs returns every recorded run in the order they were recorded. A missing file has no runs.
+func (s *Store) Runs() ([]Run, error) {
+	var runs []Run
+	err := s.read(func(kind string, line []byte) error {
+		if kind != kindRun {
+			return nil
+		}
+
+		var run Run
+		if err := json.Unmarshal(line, &run); err != nil {
+			return err
+		}
+		runs = append(runs, run)
+		return nil
+	})
+	return runs, err
+}
+
+func (s *Store) append(records ...any) error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
+
+func (s *Store) read(fn func(kind string, line []byte) error) error {
+	file, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("%s:%d is not a valid history record - %w", s.path, lineNumber, err)
+		}
+		if record.Kind == "" {
+			record.Kind = kindOutcome
+		}
+
+		if err := fn(record.Kind, scanner.Bytes()); err != nil {
+			return fmt.Errorf("%s:%d is not a valid history record - %w", s.path, lineNumber, err)
+		}
+	}
+	return scanner.Err()
+}
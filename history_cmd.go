@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/github"
+	"github.com/tamj0rd2/pipeline-status-action/history"
+)
+
+func recordRun(store *history.Store, config config, statuses []github.Status, err error, startedAt time.Time) error {
+	run := history.Run{
+		Repository:    config.owner + "/" + config.repoName,
+		Branch:        config.branch,
+		SHA:           config.sha,
+		Outcome:       result(err),
+		ErrorCategory: errorCategory(err),
+		StartedAt:     startedAt.UTC(),
+		FinishedAt:    time.Now().UTC(),
+	}
+
+	for _, status := range statuses {
+		var transitions []history.Transition
+		for _, transition := range status.Transitions {
+			transitions = append(transitions, history.Transition{State: transition.State, At: transition.At.UTC()})
+		}
+
+		run.Checks = append(run.Checks, history.RunCheck{
+			Name:            status.Name,
+			State:           status.State(),
+			Retries:         status.Retries,
+			DurationSeconds: status.Duration().Seconds(),
+			Transitions:     transitions,
+		})
+	}
+
+	return store.AppendRun(run)
+}
+
+const (
+	formatTable = "table"
+	formatCSV   = "csv"
+	formatJSON  = "json"
+)
+
+func runHistory(args []string) error {
+	flags := newFlagSet("history")
+	historyFile := flags.String("historyFile", "", "The file that runs were recorded in")
+	repository := flags.String("repository", "", "Only include runs for this repository, e.g owner/repo")
+	branch := flags.String("branch", "", "Only include runs for this branch")
+	check := flags.String("check", "", "Only include runs that tracked this check, and show how it did")
+	since := flags.String("since", "", "Only include runs that started on or after this date, e.g 2022-08-01")
+	until := flags.String("until", "", "Only include runs that started before this date, e.g 2022-09-01")
+	format := flags.String("format", formatTable, "The output format, table, csv or json")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *historyFile == "" {
+		return usageErrorf("historyFile is required")
+	}
+
+	query := history.Query{Repository: *repository, Branch: *branch, Check: *check}
+
+	var err error
+	if query.Since, err = parseDate("since", *since); err != nil {
+		return err
+	}
+	if query.Until, err = parseDate("until", *until); err != nil {
+		return err
+	}
+
+	runs, err := history.NewStore(*historyFile).Runs()
+	if err != nil {
+		return err
+	}
+	runs = history.Filter(runs, query)
+
+	switch *format {
+	case formatTable:
+		return writeHistoryTable(os.Stdout, runs, *check)
+	case formatCSV:
+		return writeHistoryCSV(os.Stdout, runs, *check)
+	case formatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if runs == nil {
+			runs = []history.Run{}
+		}
+		return encoder.Encode(runs)
+	default:
+		return usageErrorf("format must be %s, %s or %s", formatTable, formatCSV, formatJSON)
+	}
+}
+
+func parseDate(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, usageErrorf("%s must be a date in the format YYYY-MM-DD, got %q", name, value)
+	}
+	return t, nil
+}
+
+func historyRows(runs []history.Run, check string) [][]string {
+	header := []string{"STARTED", "REPOSITORY", "BRANCH", "SHA", "OUTCOME", "DURATION"}
+	if check != "" {
+		header = append(header, "CHECK STATE", "CHECK DURATION", "CHECK RETRIES")
+	}
+
+	rows := [][]string{header}
+	for _, run := range runs {
+		row := []string{
+			run.StartedAt.Format(time.RFC3339),
+			run.Repository,
+			run.Branch,
+			run.SHA,
+			run.Outcome,
+			run.Duration().Round(time.Second).String(),
+		}
+
+		if check != "" {
+			runCheck, _ := run.Check(check)
+			row = append(row,
+				runCheck.State,
+				(time.Duration(runCheck.DurationSeconds) * time.Second).String(),
+				strconv.Itoa(runCheck.Retries),
+			)
+		}
+
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func writeHistoryTable(w io.Writer, runs []history.Run, check string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range historyRows(runs, check) {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, cell)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func writeHistoryCSV(w io.Writer, runs []history.Run, check string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(historyRows(runs, check)); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,81 @@
+// Package logging builds the structured logger used everywhere, with secrets redacted from every message and attribute.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/tamj0rd2/pipeline-status-action/redact"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// New creates a logger that writes to w in the given format (text or json), dropping anything below level.
+func New(w io.Writer, format, level string, redactor *redact.Redactor) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q - %w", level, err)
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: lvl,
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			return redactAttr(redactor, attr)
+		},
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, must be %s or %s", format, FormatText, FormatJSON)
+	}
+
+	return slog.New(&redactingHandler{Handler: handler, redactor: redactor}), nil
+}
+
+func redactAttr(redactor *redact.Redactor, attr slog.Attr) slog.Attr {
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, redactor.Redact(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, redactor.Redact(err.Error()))
+		}
+		return slog.String(attr.Key, redactor.Redact(fmt.Sprint(attr.Value.Any())))
+	default:
+		return attr
+	}
+}
+
+// redactingHandler redacts the log message, which ReplaceAttr doesn't get to see for the JSON and text handlers.
+type redactingHandler struct {
+	slog.Handler
+	redactor *redact.Redactor
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(attr)
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &redactingHandler{Handler: h.Handler.WithAttrs(attrs), redactor: h.redactor}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{Handler: h.Handler.WithGroup(name), redactor: h.redactor}
+}
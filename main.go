@@ -2,102 +2,411 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/tamj0rd2/pipeline-status-action/slack"
+	"github.com/tamj0rd2/pipeline-status-action/logging"
+	"github.com/tamj0rd2/pipeline-status-action/policy"
+	"github.com/tamj0rd2/pipeline-status-action/redact"
+	"github.com/tamj0rd2/pipeline-status-action/tracing"
 
 	"github.com/tamj0rd2/pipeline-status-action/github"
 )
 
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands is in the order they're listed in the usage.
+var commands = []command{
+	{name: "wait", summary: "Wait for the checks on a commit to succeed and alert if they don't (the default)", run: runWait},
+	{name: "status", summary: "Print the current state of the checks on a commit without waiting", run: runStatus},
+	{name: "list-contexts", summary: "List the names of every status and check run reported for a commit", run: runListContexts},
+	{name: "notify-test", summary: "Send a test alert to check that a webhook works", run: runNotifyTest},
+	{name: "validate", summary: "Check the flags of the wait command, and its repoConfig file if it's here, without calling GitHub", run: runValidate},
+	{name: "serve", summary: "Run an HTTP server that waits for checks on request", run: runServe},
+	{name: "history", summary: "Show recorded runs from a history file", run: runHistory},
+	{name: "flaky-report", summary: "Show how often checks flake from a history file", run: runFlakyReport},
+	{name: "dora", summary: "Compute DORA metrics for a branch from its check history", run: runDORA},
+}
+
 func main() {
-	config, err := parseArgs()
-	if err != nil {
-		log.Println(err)
-		log.Println(`Usage: main.go -token=<token> -repository=<repository> -sha=<sha>`)
-		flag.PrintDefaults()
-		os.Exit(1)
+	name, args := "wait", os.Args[1:]
+	// flags without a command run wait, which is how the action has always been invoked.
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	ctx := context.Background()
+	if name == "help" {
+		printUsage()
+		return
+	}
 
-	service := github.NewService(ctx, config.token)
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage()
+		os.Exit(exitInvalidUsage)
+	}
 
-	if failedStatuses, err := service.WaitForChecksToSucceed(ctx, config.timeout, config.owner, config.repoName, config.sha, config.statusNames); err != nil {
-		log.Println(failedStatuses, err)
+	err := cmd.run(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		slog.Error(name+" failed", "error", err, "exitCode", exitCode(err))
+		os.Exit(exitCode(err))
+	}
+}
 
-		author, message, url := service.GetCommitInfo(ctx, config.owner, config.repoName, config.sha)
-		if err := slack.AlertThatStatusFailed(ctx, config.slackWebhookURL, url, author, message, err.Error(), failedStatuses); err != nil {
-			log.Fatal(err)
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
 		}
+	}
+	return command{}, false
+}
 
-		log.Println("slack alert sent")
-		os.Exit(1)
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: pipeline-status-action <command> [flags]\n\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(os.Stderr, "\nRun pipeline-status-action <command> -h for the flags of a command.")
+}
+
+// usageError is returned when a command was given invalid flags or arguments.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
 
-	fmt.Println("all status checks completed successfully")
+func (e usageError) Unwrap() error {
+	return e.err
 }
 
-type config struct {
-	token, sha      string
-	owner           string
-	repoName        string
-	statusNames     []string
-	slackWebhookURL string
-	timeout         time.Duration
+func usageErrorf(format string, args ...any) error {
+	return usageError{err: fmt.Errorf(format, args...)}
 }
 
-func parseArgs() (config, error) {
-	var token, repo, sha, checkNames, slackWebhookURL string
-	var timeoutMinutes int
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: pipeline-status-action %s [flags]\n", name)
+		flags.PrintDefaults()
+	}
+	return flags
+}
 
-	flag.StringVar(&token, "token", "", "GitHub token")
-	flag.StringVar(&repo, "repository", "", "GitHub repository")
-	flag.StringVar(&sha, "sha", "", "Commit SHA")
-	flag.StringVar(&checkNames, "checkNames", "", "A comma separated list of the checks to run, e.g check1,check2,check3")
-	flag.StringVar(&slackWebhookURL, "slackWebhookURL", "", "The slack webhook URL")
-	flag.IntVar(&timeoutMinutes, "timeoutMinutes", 0, "The number of minutes to timeout after")
-	flag.Parse()
+// configFlag is the flag that commands which can read the config file take its path from.
+const configFlag = "config"
 
-	if token == "" {
-		return config{}, fmt.Errorf("token is required")
+// parseFlags parses the flags of a command, treating anything left over as a mistake rather than ignoring it.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(withoutEmptyFlags(args)); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError{err: err}
 	}
 
-	if repo == "" {
-		return config{}, fmt.Errorf("repository is required")
+	if flags.NArg() > 0 {
+		return usageErrorf("%s doesn't take arguments, got %q", flags.Name(), flags.Args())
 	}
+	return nil
+}
 
+// targetFlags identify a commit. They default to the environment GitHub Actions provides.
+type targetFlags struct {
+	token, repository, sha *string
+}
+
+func addTargetFlags(flags *flag.FlagSet) targetFlags {
+	return targetFlags{
+		token:      flags.String("token", "", "GitHub token. Defaults to $GITHUB_TOKEN"),
+		repository: flags.String("repository", "", "GitHub repository, e.g owner/repo. Defaults to $GITHUB_REPOSITORY"),
+		sha:        flags.String("sha", "", "Commit SHA. Defaults to $GITHUB_SHA"),
+	}
+}
+
+func (f targetFlags) parse() (token, owner, repoName, sha string, err error) {
+	token = orEnv(*f.token, "GITHUB_TOKEN")
+	if token == "" {
+		return "", "", "", "", usageErrorf("token is required")
+	}
+
+	sha = orEnv(*f.sha, "GITHUB_SHA")
 	if sha == "" {
-		return config{}, fmt.Errorf("sha is required")
+		return "", "", "", "", usageErrorf("sha is required")
 	}
 
-	if checkNames == "" {
-		return config{}, fmt.Errorf("checkNames is required")
+	owner, repoName, err = splitRepository(orEnv(*f.repository, "GITHUB_REPOSITORY"))
+	if err != nil {
+		return "", "", "", "", err
 	}
 
-	if slackWebhookURL == "" {
-		return config{}, fmt.Errorf("slackWebhookURL is required")
+	return token, owner, repoName, sha, nil
+}
+
+// orEnv falls back to an environment variable when a flag wasn't given. Flags don't default to the variable
+// directly, because defaults are printed in the usage and the token would end up in it.
+func orEnv(value, key string) string {
+	if value == "" {
+		return os.Getenv(key)
 	}
+	return value
+}
+
+type logFlags struct {
+	format, level *string
+}
 
-	if timeoutMinutes == 0 {
-		return config{}, fmt.Errorf("timeoutMinutes is required")
+func addLogFlags(flags *flag.FlagSet) logFlags {
+	return logFlags{
+		format: flags.String("logFormat", logging.FormatText, "The format to log in, text or json"),
+		level:  flags.String("logLevel", "info", "The minimum level to log at, debug, info, warn or error"),
 	}
+}
 
-	splitRepo := strings.SplitN(repo, "/", 2)
-	owner := splitRepo[0]
-	repoName := splitRepo[1]
+// setDefaultLogger makes the default logger use the configured format and level, redacting the given secrets and
+// anything that matches the given patterns. The redactor is returned so that it can be used for alerts too.
+func (f logFlags) setDefaultLogger(secrets, patterns []string) (*redact.Redactor, error) {
+	redactor, err := redact.New(secrets, append(redact.DefaultPatterns, patterns...))
+	if err != nil {
+		return nil, usageError{err: err}
+	}
+
+	logger, err := logging.New(os.Stderr, *f.format, *f.level, redactor)
+	if err != nil {
+		return nil, usageError{err: err}
+	}
+	slog.SetDefault(logger)
+
+	return redactor, nil
+}
+
+func exportTrace(ctx context.Context, tracer *tracing.Tracer, config config) {
+	if config.traceFile != "" {
+		if err := tracer.WriteFile(config.traceFile); err != nil {
+			slog.Warn("failed to write trace", "error", err)
+		}
+	}
+
+	if config.otlpEndpoint != "" {
+		if err := tracer.Export(ctx, config.otlpEndpoint); err != nil {
+			slog.Warn("failed to export trace", "error", err)
+		}
+	}
+}
+
+// getCommitInfo falls back to just the SHA and URL of the commit if its details can't be retrieved, because they're
+// only there to make alerts and reports more helpful.
+func getCommitInfo(ctx context.Context, service *github.Service, config config) github.CommitInfo {
+	commit, err := service.GetCommitInfo(ctx, config.owner, config.repoName, config.sha)
+	if err != nil {
+		slog.Warn("failed to get commit info", "error", err)
+		return github.CommitInfo{SHA: config.sha, HTMLURL: github.CommitURL(config.owner, config.repoName, config.sha)}
+	}
+	return commit
+}
+
+// splitRepository splits a repository in the format owner/repo into its owner and name.
+func splitRepository(repository string) (string, string, error) {
+	owner, name, ok := strings.Cut(repository, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", usageErrorf("repository must be in the format owner/repo, got %q", repository)
+	}
+	return owner, name, nil
+}
 
-	return config{
-		token:           token,
-		sha:             sha,
-		owner:           owner,
-		repoName:        repoName,
-		statusNames:     strings.Split(checkNames, ","),
-		slackWebhookURL: slackWebhookURL,
-		timeout:         time.Minute * time.Duration(timeoutMinutes),
-	}, nil
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseRetryPolicies(s string) ([]github.RetryPolicy, error) {
+	var policies []github.RetryPolicy
+	for _, item := range splitList(s) {
+		pattern, retries, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, usageErrorf("retries must be in the format pattern=count, got %q", item)
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, usageErrorf("invalid retry pattern %q - %w", pattern, err)
+		}
+
+		maxRetries, err := strconv.Atoi(retries)
+		if err != nil || maxRetries < 0 {
+			return nil, usageErrorf("retry count for %q must be a whole number of 0 or more, got %q", pattern, retries)
+		}
+
+		policies = append(policies, github.RetryPolicy{Pattern: pattern, MaxRetries: maxRetries})
+	}
+	return policies, nil
+}
+
+// severities are the values the severities flag accepts.
+var severities = []string{github.SeverityRequired, github.SeverityWarn, github.SeverityInfo}
+
+// parseSeverities parses a list like perf-*=info,coverage=warn.
+func parseSeverities(s string) ([]github.CheckSeverity, error) {
+	var parsed []github.CheckSeverity
+	for _, item := range splitList(s) {
+		pattern, severity, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, usageErrorf("severities must be in the format pattern=severity, got %q", item)
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, usageErrorf("invalid severity pattern %q - %w", pattern, err)
+		}
+
+		if !slices.Contains(severities, severity) {
+			return nil, usageErrorf("severity for %q must be one of %s, got %q", pattern, strings.Join(severities, ", "), severity)
+		}
+
+		parsed = append(parsed, github.CheckSeverity{Pattern: pattern, Severity: severity})
+	}
+	return parsed, nil
+}
+
+// parseStages parses a list like build=build,lint;test=unit-*,e2e-*, where the stages are in the order they run.
+func parseStages(s string) ([]github.Stage, error) {
+	var stages []github.Stage
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(s, ";") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		name, checks, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || len(splitList(checks)) == 0 {
+			return nil, usageErrorf("stages must be in the format stage=check1,check2;stage2=check3, got %q", item)
+		}
+		if seen[name] {
+			return nil, usageErrorf("stage %q is listed more than once", name)
+		}
+		seen[name] = true
+
+		for _, pattern := range splitList(checks) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, usageErrorf("invalid check pattern %q in stage %q - %w", pattern, name, err)
+			}
+		}
+		stages = append(stages, github.Stage{Name: name, Checks: splitList(checks)})
+	}
+	return stages, nil
+}
+
+// checkStagesMatch makes sure that every stage has at least one of the checks being waited for, because a stage that
+// doesn't is almost certainly a typo.
+func checkStagesMatch(stages []github.Stage, checkNames []string, gatePolicy *policy.Policy) error {
+	if gatePolicy != nil {
+		checkNames = append(append([]string(nil), checkNames...), gatePolicy.Checks()...)
+	}
+
+	for _, stage := range stages {
+		matched := false
+		for _, pattern := range stage.Checks {
+			for _, name := range checkNames {
+				if ok, _ := path.Match(pattern, name); ok {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return usageErrorf("stage %q doesn't match any of the checks being waited for", stage.Name)
+		}
+	}
+	return nil
+}
+
+// checkSomeRequired makes sure that at least one of the checks is required, because otherwise nothing could fail the
+// wait. A policy decides the result on its own, whatever the severities.
+func checkSomeRequired(checkNames []string, severities []github.CheckSeverity, gatePolicy *policy.Policy) error {
+	if gatePolicy != nil {
+		return nil
+	}
+
+	for _, name := range checkNames {
+		if github.FindSeverity(severities, name) == github.SeverityRequired {
+			return nil
+		}
+	}
+	return usageErrorf("at least one check has to be required, but the severities make all of %s warn or info", strings.Join(checkNames, ", "))
+}
+
+// checkTimeoutSettings are the deadlines that can be given for a pattern in the checkTimeouts flag.
+var checkTimeoutSettings = map[string]func(*github.CheckTimeouts) *time.Duration{
+	"appear":   func(t *github.CheckTimeouts) *time.Duration { return &t.AppearWithin },
+	"finish":   func(t *github.CheckTimeouts) *time.Duration { return &t.FinishWithin },
+	"expected": func(t *github.CheckTimeouts) *time.Duration { return &t.ExpectedDuration },
+}
+
+// parseCheckTimeouts parses a list like lint=appear:2m finish:5m,e2e-*=finish:50m expected:30m.
+func parseCheckTimeouts(s string) ([]github.CheckTimeouts, error) {
+	var timeouts []github.CheckTimeouts
+	for _, item := range splitList(s) {
+		pattern, settings, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(settings) == "" {
+			return nil, usageErrorf("checkTimeouts must be in the format pattern=appear:2m finish:5m expected:3m, got %q", item)
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, usageErrorf("invalid check timeouts pattern %q - %w", pattern, err)
+		}
+
+		t := github.CheckTimeouts{Pattern: pattern}
+		for _, setting := range strings.Fields(settings) {
+			name, value, _ := strings.Cut(setting, ":")
+			field, ok := checkTimeoutSettings[name]
+			if !ok {
+				return nil, usageErrorf("check timeouts for %q can only set appear, finish and expected, got %q", pattern, setting)
+			}
+
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, usageErrorf("check timeout %s for %q must be a positive duration like 90s or 5m, got %q", name, pattern, value)
+			}
+			*field(&t) = d
+		}
+		timeouts = append(timeouts, t)
+	}
+	return timeouts, nil
+}
+
+// formatCheckTimeouts is the inverse of parseCheckTimeouts.
+func formatCheckTimeouts(timeouts []github.CheckTimeouts) string {
+	var items []string
+	for _, t := range timeouts {
+		var settings []string
+		for _, name := range []string{"appear", "finish", "expected"} {
+			if d := *checkTimeoutSettings[name](&t); d > 0 {
+				settings = append(settings, name+":"+d.String())
+			}
+		}
+		items = append(items, t.Pattern+"="+strings.Join(settings, " "))
+	}
+	return strings.Join(items, ",")
 }
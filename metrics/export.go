@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	prometheusContentType  = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// WriteFile writes the metrics to a file in the OpenMetrics format, e.g for node_exporter's textfile collector.
+func (r *Registry) WriteFile(path string) error {
+	var buf bytes.Buffer
+	if err := r.Write(&buf, true); err != nil {
+		return err
+	}
+
+	// write then rename, so that a collector never reads a half written file.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Push replaces the metrics for the given job on a Pushgateway compatible endpoint.
+func (r *Registry) Push(ctx context.Context, gatewayURL, job string) error {
+	var buf bytes.Buffer
+	if err := r.Write(&buf, false); err != nil {
+		return err
+	}
+
+	pushURL := strings.TrimSuffix(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, pushURL, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", prometheusContentType)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected status code from pushgateway: %d %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Handler serves the metrics for scraping, in the OpenMetrics format if the scraper asks for it.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		openMetrics := strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text")
+		if openMetrics {
+			w.Header().Set("Content-Type", openMetricsContentType)
+		} else {
+			w.Header().Set("Content-Type", prometheusContentType)
+		}
+
+		if err := r.Write(w, openMetrics); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
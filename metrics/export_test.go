@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestRegistry() *Registry {
+	r := NewRegistry()
+	r.AddCounter("pipeline_status_runs", "Runs of the gate.", Labels{"result": "success"}, 2)
+	r.SetGauge("pipeline_status_checks", "Checks being waited for.", nil, 3)
+	r.Observe("pipeline_status_wait_seconds", "How long the wait took.", []float64{60, 300}, Labels{"repo": `o/"r"`}, 120)
+	return r
+}
+
+func TestPush(t *testing.T) {
+	var (
+		method, path, contentType string
+		body                      []byte
+	)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, contentType = r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	if err := newTestRegistry().Push(context.Background(), gateway.URL+"/", "pipeline status"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if method != http.MethodPut {
+		t.Errorf("expected a PUT, got %s", method)
+	}
+	if want := "/metrics/job/pipeline%20status"; path != want {
+		t.Errorf("expected the path %s, got %s", want, path)
+	}
+	if contentType != prometheusContentType {
+		t.Errorf("expected the content type %q, got %q", prometheusContentType, contentType)
+	}
+
+	for _, want := range []string{
+		"# TYPE pipeline_status_runs_total counter\n",
+		`pipeline_status_runs_total{result="success"} 2` + "\n",
+		"pipeline_status_checks 3\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected the pushed metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(string(body), "# EOF") {
+		t.Errorf("expected the pushed metrics to be in the Prometheus format, got:\n%s", body)
+	}
+}
+
+func TestPushFailure(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "push rejected", http.StatusBadRequest)
+	}))
+	defer gateway.Close()
+
+	err := newTestRegistry().Push(context.Background(), gateway.URL, "pipeline-status")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if want := "unexpected status code from pushgateway: 400 push rejected"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestRegistry().Write(&buf, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `# HELP pipeline_status_runs Runs of the gate.
+# TYPE pipeline_status_runs counter
+pipeline_status_runs_total{result="success"} 2
+# HELP pipeline_status_checks Checks being waited for.
+# TYPE pipeline_status_checks gauge
+pipeline_status_checks 3
+# HELP pipeline_status_wait_seconds How long the wait took.
+# TYPE pipeline_status_wait_seconds histogram
+pipeline_status_wait_seconds_bucket{le="60",repo="o/\"r\""} 0
+pipeline_status_wait_seconds_bucket{le="300",repo="o/\"r\""} 1
+pipeline_status_wait_seconds_bucket{le="+Inf",repo="o/\"r\""} 1
+pipeline_status_wait_seconds_sum{repo="o/\"r\""} 120
+pipeline_status_wait_seconds_count{repo="o/\"r\""} 1
+# EOF
+`
+	if buf.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestHandlerNegotiatesTheFormat(t *testing.T) {
+	handler := newTestRegistry().Handler()
+
+	tests := []struct {
+		accept, contentType string
+		eof                 bool
+	}{
+		{accept: "application/openmetrics-text; version=1.0.0", contentType: openMetricsContentType, eof: true},
+		{accept: "text/plain", contentType: prometheusContentType, eof: false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", tt.accept)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		if got := res.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("Accept %q: expected the content type %q, got %q", tt.accept, tt.contentType, got)
+		}
+		if got := strings.HasSuffix(res.Body.String(), "# EOF\n"); got != tt.eof {
+			t.Errorf("Accept %q: expected ending with # EOF to be %v, got:\n%s", tt.accept, tt.eof, res.Body.String())
+		}
+	}
+}
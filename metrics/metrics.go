@@ -0,0 +1,212 @@
+// Package metrics is a small registry of counters, gauges and histograms that can be written in the Prometheus text
+// or OpenMetrics formats, pushed to a Pushgateway or scraped over HTTP.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// Labels are the label names and values that identify a single series within a metric.
+type Labels map[string]string
+
+func (l Labels) key() string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []string
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escapeLabelValue(l[name])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+type series struct {
+	labels Labels
+	value  float64
+	// buckets and count are only used by histograms. buckets[i] counts the observations <= the family's bounds[i].
+	buckets []uint64
+	count   uint64
+	valueFn func() float64
+}
+
+type family struct {
+	name    string
+	help    string
+	typ     metricType
+	bounds  []float64
+	series  map[string]*series
+	ordered []string
+}
+
+// Registry holds every metric that will be written out. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+	ordered  []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+func (r *Registry) family(name, help string, typ metricType, bounds []float64) *family {
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ, bounds: bounds, series: make(map[string]*series)}
+		r.families[name] = f
+		r.ordered = append(r.ordered, name)
+	}
+	return f
+}
+
+func (f *family) get(labels Labels) *series {
+	key := labels.key()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: labels}
+		if f.typ == typeHistogram {
+			s.buckets = make([]uint64, len(f.bounds))
+		}
+		f.series[key] = s
+		f.ordered = append(f.ordered, key)
+	}
+	return s
+}
+
+// AddCounter increases a counter. Counter names should not include the _total suffix, it is added when written.
+func (r *Registry) AddCounter(name, help string, labels Labels, delta float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.family(name, help, typeCounter, nil).get(labels).value += delta
+}
+
+func (r *Registry) SetGauge(name, help string, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.family(name, help, typeGauge, nil).get(labels).value = value
+}
+
+// GaugeFunc registers a gauge whose value is read from fn whenever the metrics are written.
+func (r *Registry) GaugeFunc(name, help string, labels Labels, fn func() float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.family(name, help, typeGauge, nil).get(labels).valueFn = fn
+}
+
+// CounterFunc registers a counter whose value is read from fn whenever the metrics are written.
+func (r *Registry) CounterFunc(name, help string, labels Labels, fn func() float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.family(name, help, typeCounter, nil).get(labels).valueFn = fn
+}
+
+// Observe adds a value to a histogram. The bucket bounds are fixed by the first observation of the metric.
+func (r *Registry) Observe(name, help string, bounds []float64, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f := r.family(name, help, typeHistogram, bounds)
+	s := f.get(labels)
+	for i, bound := range f.bounds {
+		if value <= bound {
+			s.buckets[i]++
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+// Write writes every metric in the Prometheus text format, or the OpenMetrics format if openMetrics is true.
+func (r *Registry) Write(w io.Writer, openMetrics bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sb strings.Builder
+	for _, name := range r.ordered {
+		f := r.families[name]
+
+		typeName := f.name
+		if f.typ == typeCounter && !openMetrics {
+			typeName += "_total"
+		}
+		fmt.Fprintf(&sb, "# HELP %s %s\n", typeName, f.help)
+		fmt.Fprintf(&sb, "# TYPE %s %s\n", typeName, f.typ)
+
+		for _, key := range f.ordered {
+			s := f.series[key]
+			value := s.value
+			if s.valueFn != nil {
+				value = s.valueFn()
+			}
+
+			switch f.typ {
+			case typeCounter:
+				writeSample(&sb, f.name+"_total", s.labels, nil, value)
+			case typeGauge:
+				writeSample(&sb, f.name, s.labels, nil, value)
+			case typeHistogram:
+				for i, bound := range f.bounds {
+					writeSample(&sb, f.name+"_bucket", s.labels, Labels{"le": formatFloat(bound)}, float64(s.buckets[i]))
+				}
+				writeSample(&sb, f.name+"_bucket", s.labels, Labels{"le": "+Inf"}, float64(s.count))
+				writeSample(&sb, f.name+"_sum", s.labels, nil, value)
+				writeSample(&sb, f.name+"_count", s.labels, nil, float64(s.count))
+			}
+		}
+	}
+
+	if openMetrics {
+		sb.WriteString("# EOF\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeSample(sb *strings.Builder, name string, labels, extra Labels, value float64) {
+	all := make(Labels, len(labels)+len(extra))
+	for k, v := range labels {
+		all[k] = v
+	}
+	for k, v := range extra {
+		all[k] = v
+	}
+
+	if key := all.key(); key != "" {
+		fmt.Fprintf(sb, "%s{%s} %s\n", name, key, formatFloat(value))
+	} else {
+		fmt.Fprintf(sb, "%s %s\n", name, formatFloat(value))
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tamj0rd2/pipeline-status-action/github"
+	"github.com/tamj0rd2/pipeline-status-action/slack"
+)
+
+func runNotifyTest(args []string) error {
+	flags := newFlagSet("notify-test")
+	log := addLogFlags(flags)
+	slackWebhookURL := flags.String("slackWebhookURL", "", "The slack webhook URL to send the test alert to")
+	repository := flags.String("repository", "owner/repo", "The repository to mention in the test alert, e.g owner/repo")
+	redactPatterns := flags.String("redactPatterns", "", "A newline separated list of regular expressions to redact from the test alert")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *slackWebhookURL == "" {
+		return usageErrorf("slackWebhookURL is required")
+	}
+
+	owner, repoName, err := splitRepository(*repository)
+	if err != nil {
+		return err
+	}
+
+	redactor, err := log.setDefaultLogger([]string{*slackWebhookURL}, strings.Split(*redactPatterns, "\n"))
+	if err != nil {
+		return err
+	}
+
+	const sha = "0000000000000000000000000000000000000000"
+	alert := slack.Alert{
+		Commit: github.CommitInfo{
+			SHA:     sha,
+			HTMLURL: github.CommitURL(owner, repoName, sha),
+			Subject: "This is a test alert from pipeline-status-action",
+		},
+		ErrorMessage:   "this is a test, nothing has failed",
+		FailedStatuses: []github.Status{{Name: "example-check", Url: fmt.Sprintf("https://github.com/%s/%s/actions", owner, repoName)}},
+	}
+	if err := slack.AlertThatStatusFailed(context.Background(), *slackWebhookURL, redactor, alert); err != nil {
+		return err
+	}
+
+	fmt.Println("test alert sent")
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/actions"
+	"github.com/tamj0rd2/pipeline-status-action/github"
+)
+
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+	resultTimeout = "timeout"
+	resultError   = "error"
+)
+
+// result categorises the outcome of waiting for the checks.
+func result(err error) string {
+	switch exitCode(err) {
+	case exitSuccess:
+		return resultSuccess
+	case exitChecksFailed:
+		return resultFailure
+	case exitTimedOut, exitChecksMissing:
+		return resultTimeout
+	default:
+		return resultError
+	}
+}
+
+// writeActionsOutputs sets the step outputs and appends the step summary so that later steps can react to the result.
+func writeActionsOutputs(statuses []github.Status, stages []github.StageResult, err error, elapsed time.Duration) error {
+	// warn and info checks that failed have their own output, or none at all, because they didn't fail the gate.
+	failedChecks, jsonErr := json.Marshal(github.StatusNames(github.RequiredStatuses(github.FailedStatuses(statuses))))
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	incompleteChecks, jsonErr := json.Marshal(github.StatusNames(github.IncompleteStatuses(statuses)))
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	pendingChecks, jsonErr := json.Marshal(github.StatusNames(statusesInState(statuses, github.StatePending)))
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	missingChecks, jsonErr := json.Marshal(github.StatusNames(statusesInState(statuses, github.StateMissing)))
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	warningChecks, jsonErr := json.Marshal(github.StatusNames(github.NonBlockingFailures(statuses, github.SeverityWarn)))
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	outputs := []struct{ name, value string }{
+		{"result", result(err)},
+		{"failed-checks", string(failedChecks)},
+		{"incomplete-checks", string(incompleteChecks)},
+		{"pending-checks", string(pendingChecks)},
+		{"missing-checks", string(missingChecks)},
+		{"warning-checks", string(warningChecks)},
+		{"failed-stage", failedStageName(stages)},
+		{"elapsed-seconds", fmt.Sprintf("%d", int(elapsed.Seconds()))},
+	}
+	for _, output := range outputs {
+		if err := actions.SetOutput(output.name, output.value); err != nil {
+			return err
+		}
+	}
+
+	return actions.AppendStepSummary(stepSummary(statuses, stages, err))
+}
+
+// failedStageName returns the name of the first stage that failed, or nothing if none did.
+func failedStageName(stages []github.StageResult) string {
+	for _, stage := range stages {
+		if stage.State == github.StateFailure {
+			return stage.Name
+		}
+	}
+	return ""
+}
+
+var stageEmoji = map[string]string{
+	github.StateSuccess: ":white_check_mark:",
+	github.StateFailure: ":x:",
+	github.StatePending: ":hourglass_flowing_sand:",
+	github.StateMissing: ":white_circle:",
+	github.StateSkipped: ":fast_forward:",
+}
+
+func stepSummary(statuses []github.Status, stages []github.StageResult, err error) string {
+	var sb strings.Builder
+	sb.WriteString("## Pipeline status\n\n")
+	if err != nil {
+		fmt.Fprintf(&sb, ":x: %s\n\n", err)
+	} else {
+		sb.WriteString(":white_check_mark: all required status checks completed successfully\n\n")
+	}
+
+	if warnings := github.NonBlockingFailures(statuses, github.SeverityWarn); len(warnings) > 0 {
+		fmt.Fprintf(&sb, ":warning: non-blocking checks failed - %s\n\n", strings.Join(github.StatusNames(warnings), ", "))
+	}
+
+	if len(stages) > 0 {
+		var steps []string
+		for _, stage := range stages {
+			steps = append(steps, stageEmoji[stage.State]+" "+stage.Name)
+		}
+		fmt.Fprintf(&sb, "**Stages**: %s\n\n", strings.Join(steps, " → "))
+	}
+
+	sb.WriteString("| Check | Severity | State | Duration | Link |\n")
+	sb.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, status := range statuses {
+		link := ""
+		if status.Url != "" {
+			link = fmt.Sprintf("[details](%s)", status.Url)
+		}
+
+		duration := ""
+		if status.Duration() > 0 {
+			duration = status.Duration().Round(time.Second).String()
+		}
+
+		state := status.State()
+		if didYouMean := status.DidYouMean(); didYouMean != "" {
+			state += " (" + didYouMean + ")"
+		}
+
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s |\n", escapeTableCell(status.Name), status.Severity, escapeTableCell(state), duration, link)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func statusesInState(statuses []github.Status, state string) []github.Status {
+	var matching []github.Status
+	for _, status := range statuses {
+		if status.State() == state {
+			matching = append(matching, status)
+		}
+	}
+	return matching
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/github"
+	"github.com/tamj0rd2/pipeline-status-action/metrics"
+)
+
+var durationBuckets = []float64{30, 60, 120, 300, 600, 1200, 1800, 3600, 7200}
+
+func registerAPIMetrics(registry *metrics.Registry, service *github.Service) {
+	registry.CounterFunc("pipeline_status_github_api_requests", "The number of requests made to the GitHub API.", nil, func() float64 {
+		return float64(service.APIStats().Requests)
+	})
+	registry.CounterFunc("pipeline_status_github_api_errors", "The number of requests to the GitHub API that failed.", nil, func() float64 {
+		return float64(service.APIStats().Errors)
+	})
+	registry.GaugeFunc("pipeline_status_github_rate_limit_remaining", "The number of GitHub API requests left in the current rate limit window.", nil, func() float64 {
+		return float64(service.APIStats().RateLimitRemaining)
+	})
+}
+
+func recordCheckMetrics(registry *metrics.Registry, repository string, statuses []github.Status, waitStartedAt time.Time) {
+	for _, status := range statuses {
+		labels := metrics.Labels{"repository": repository, "check": status.Name}
+
+		registry.AddCounter(
+			"pipeline_status_check_outcomes",
+			"The number of times each check finished in each state.",
+			metrics.Labels{"repository": repository, "check": status.Name, "outcome": status.State()},
+			1,
+		)
+
+		if status.Finished {
+			registry.Observe("pipeline_status_check_duration_seconds", "How long each check was seen running for.", durationBuckets, labels, status.Duration().Seconds())
+		}
+
+		if !status.StartedAt.IsZero() {
+			timeToStart := status.StartedAt.Sub(waitStartedAt)
+			if timeToStart < 0 {
+				timeToStart = 0
+			}
+			registry.Observe("pipeline_status_check_time_to_start_seconds", "How long after waiting began each check started, according to GitHub, or 0 if it had already started.", durationBuckets, labels, timeToStart.Seconds())
+		}
+	}
+}
+
+// serveMetrics makes the metrics scrapeable at /metrics for as long as the process runs.
+func serveMetrics(addr string, registry *metrics.Registry) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry.Handler())
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			slog.Error("metrics server stopped", "error", err)
+		}
+	}()
+}
+
+func exportMetrics(ctx context.Context, registry *metrics.Registry, config config) {
+	if config.metricsFile != "" {
+		if err := registry.WriteFile(config.metricsFile); err != nil {
+			slog.Warn("failed to write metrics", "error", err)
+		}
+	}
+
+	if config.pushgatewayURL != "" {
+		if err := registry.Push(ctx, config.pushgatewayURL, "pipeline-status-action"); err != nil {
+			slog.Warn("failed to push metrics", "error", err)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Parse reads a policy expression. From the loosest binding to the tightest, it can contain:
+//
+//	a OR b
+//	a AND b
+//	a optional
+//	NOT a
+//	at least 2 of (a, b, c)
+//	(a)
+//
+// Keywords are case insensitive. A check name that contains spaces, parentheses, commas or quotes, or that is a
+// keyword, has to be quoted, e.g "status with spaces".
+func Parse(s string) (Policy, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return Policy{}, err
+	}
+	if len(tokens) == 0 {
+		return Policy{}, errors.New("the policy is empty")
+	}
+
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return Policy{}, err
+	}
+	if !p.done() {
+		return Policy{}, fmt.Errorf("unexpected %s at position %d", p.peek(), p.peek().pos)
+	}
+	return Policy{expr: e}, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenQuoted
+	tokenOpen
+	tokenClose
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is where the token starts, counting from 1.
+	pos int
+}
+
+func (t token) String() string {
+	if t.kind == tokenQuoted {
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// is reports whether the token is the given keyword.
+func (t token) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+var keywords = []string{"and", "or", "not", "optional"}
+
+func isKeyword(word string) bool {
+	for _, keyword := range keywords {
+		if strings.EqualFold(word, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpecial(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`(),"`, r)
+}
+
+func needsQuotes(name string) bool {
+	return name == "" || isKeyword(name) || strings.IndexFunc(name, isSpecial) >= 0
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "(", pos: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")", pos: i + 1})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i + 1})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated quote at position %d", i+1)
+			}
+
+			text, err := strconv.Unquote(string(runes[i : end+1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted name at position %d - %w", i+1, err)
+			}
+			tokens = append(tokens, token{kind: tokenQuoted, text: text, pos: i + 1})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !isSpecial(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[i:end]), pos: i + 1})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) done() bool {
+	return p.next >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+// accept consumes the next token if it's the given keyword.
+func (p *parser) accept(keyword string) bool {
+	if !p.done() && p.peek().is(keyword) {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, what string) error {
+	if p.done() {
+		return fmt.Errorf("expected %s but the policy ended", what)
+	}
+	if p.peek().kind != kind {
+		return fmt.Errorf("expected %s at position %d, got %s", what, p.peek().pos, p.peek())
+	}
+	p.next++
+	return nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	operands, err := p.parseOperands("or", p.parseAnd)
+	if err != nil {
+		return nil, err
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return orExpr{operands: operands}, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	operands, err := p.parseOperands("and", p.parseOptional)
+	if err != nil {
+		return nil, err
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return andExpr{operands: operands}, nil
+}
+
+// parseOperands parses one or more operands separated by the given keyword.
+func (p *parser) parseOperands(keyword string, parseOperand func() (expr, error)) ([]expr, error) {
+	var operands []expr
+	for {
+		operand, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+
+		if !p.accept(keyword) {
+			return operands, nil
+		}
+	}
+}
+
+func (p *parser) parseOptional() (expr, error) {
+	e, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("optional") {
+		return optionalExpr{operand: e}, nil
+	}
+	return e, nil
+}
+
+func (p *parser) parseNot() (expr, error) {
+	if p.accept("not") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	if p.done() {
+		return nil, errors.New("expected a check name or ( but the policy ended")
+	}
+
+	t := p.peek()
+	switch {
+	case t.kind == tokenOpen:
+		p.next++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenClose, ")"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case t.is("at") && p.next+1 < len(p.tokens) && p.tokens[p.next+1].is("least"):
+		p.next += 2
+		return p.parseAtLeast()
+	case t.kind == tokenQuoted:
+		p.next++
+		return checkExpr{name: t.text}, nil
+	case t.kind == tokenWord && !isKeyword(t.text):
+		p.next++
+		return checkExpr{name: t.text}, nil
+	default:
+		return nil, fmt.Errorf("expected a check name or ( at position %d, got %s", t.pos, t)
+	}
+}
+
+// parseAtLeast parses the rest of at least n of (a, b, c).
+func (p *parser) parseAtLeast() (expr, error) {
+	if p.done() {
+		return nil, errors.New("expected a number after at least but the policy ended")
+	}
+
+	t := p.peek()
+	n, err := strconv.Atoi(t.text)
+	if t.kind != tokenWord || err != nil || n < 1 {
+		return nil, fmt.Errorf("expected a number of 1 or more after at least at position %d, got %s", t.pos, t)
+	}
+	p.next++
+
+	if !p.accept("of") {
+		return nil, fmt.Errorf("expected of after at least %d", n)
+	}
+	if err := p.expect(tokenOpen, "( after of"); err != nil {
+		return nil, err
+	}
+
+	var operands []expr
+	for {
+		operand, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+
+		if p.done() || p.peek().kind != tokenComma {
+			break
+		}
+		p.next++
+	}
+	if err := p.expect(tokenClose, ") or ,"); err != nil {
+		return nil, err
+	}
+
+	if n > len(operands) {
+		return nil, fmt.Errorf("at least %d of (...) only lists %d", n, len(operands))
+	}
+	return atLeastExpr{n: n, operands: operands}, nil
+}
@@ -0,0 +1,328 @@
+// Package policy evaluates gate policies, boolean expressions over the outcomes of checks like
+// build AND (e2e-chrome OR e2e-firefox). Checks that are still running are unknown rather than passed or failed, so
+// a policy can be decided as soon as the checks that have finished are enough to decide it.
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Value is the outcome of a check or of a policy.
+type Value int
+
+const (
+	Unknown Value = iota
+	False
+	True
+)
+
+func (v Value) String() string {
+	switch v {
+	case True:
+		return "passed"
+	case False:
+		return "failed"
+	default:
+		return "undecided"
+	}
+}
+
+// Outcome returns the outcome of the check with the given name.
+type Outcome func(check string) Value
+
+// Policy is a parsed policy expression.
+type Policy struct {
+	expr expr
+}
+
+// Eval returns True or False once the outcome of the policy can't change, whatever the checks that are still
+// unknown turn out to be, or Unknown until then.
+func (p Policy) Eval(outcome Outcome) Value {
+	return p.expr.eval(outcome)
+}
+
+// FailedClause returns the smallest part of the policy that explains why it failed, along with the checks in that
+// part that failed. It should only be called once Eval has returned False.
+func (p Policy) FailedClause(outcome Outcome) (string, []string) {
+	clause := p.expr.failedClause(outcome)
+
+	var failed []string
+	for _, check := range checksOf(clause) {
+		if outcome(check) == False {
+			failed = append(failed, check)
+		}
+	}
+	return clause.String(), failed
+}
+
+// Checks returns every check that the policy names, in the order they first appear.
+func (p Policy) Checks() []string {
+	return checksOf(p.expr)
+}
+
+// OptionalChecks returns the checks that the policy marks optional, in the order they first appear.
+func (p Policy) OptionalChecks() []string {
+	var checks []string
+	seen := make(map[string]bool)
+	var visit func(e expr, optional bool)
+	visit = func(e expr, optional bool) {
+		switch e := e.(type) {
+		case checkExpr:
+			if optional && !seen[e.name] {
+				seen[e.name] = true
+				checks = append(checks, e.name)
+			}
+		case andExpr:
+			for _, operand := range e.operands {
+				visit(operand, optional)
+			}
+		case orExpr:
+			for _, operand := range e.operands {
+				visit(operand, optional)
+			}
+		case atLeastExpr:
+			for _, operand := range e.operands {
+				visit(operand, optional)
+			}
+		case notExpr:
+			visit(e.operand, optional)
+		case optionalExpr:
+			visit(e.operand, true)
+		}
+	}
+	visit(p.expr, false)
+	return checks
+}
+
+func (p Policy) String() string {
+	return p.expr.String()
+}
+
+func checksOf(e expr) []string {
+	var checks []string
+	seen := make(map[string]bool)
+	e.checks(func(check string) {
+		if !seen[check] {
+			seen[check] = true
+			checks = append(checks, check)
+		}
+	})
+	return checks
+}
+
+type expr interface {
+	eval(outcome Outcome) Value
+	// failedClause returns the part of the expression that made it fail.
+	failedClause(outcome Outcome) expr
+	checks(add func(check string))
+	String() string
+}
+
+type checkExpr struct {
+	name string
+}
+
+func (e checkExpr) eval(outcome Outcome) Value {
+	return outcome(e.name)
+}
+
+func (e checkExpr) failedClause(Outcome) expr {
+	return e
+}
+
+func (e checkExpr) checks(add func(string)) {
+	add(e.name)
+}
+
+func (e checkExpr) String() string {
+	if needsQuotes(e.name) {
+		return fmt.Sprintf("%q", e.name)
+	}
+	return e.name
+}
+
+// andExpr fails as soon as any of its operands fails, and passes once all of them have.
+type andExpr struct {
+	operands []expr
+}
+
+func (e andExpr) eval(outcome Outcome) Value {
+	result := True
+	for _, operand := range e.operands {
+		switch operand.eval(outcome) {
+		case False:
+			return False
+		case Unknown:
+			result = Unknown
+		}
+	}
+	return result
+}
+
+func (e andExpr) failedClause(outcome Outcome) expr {
+	for _, operand := range e.operands {
+		if operand.eval(outcome) == False {
+			return operand.failedClause(outcome)
+		}
+	}
+	return e
+}
+
+func (e andExpr) checks(add func(string)) {
+	for _, operand := range e.operands {
+		operand.checks(add)
+	}
+}
+
+func (e andExpr) String() string {
+	return joinOperands(e.operands, " AND ", func(operand expr) bool {
+		_, ok := operand.(orExpr)
+		return ok
+	})
+}
+
+// orExpr passes as soon as any of its operands passes, and fails once all of them have failed.
+type orExpr struct {
+	operands []expr
+}
+
+func (e orExpr) eval(outcome Outcome) Value {
+	result := False
+	for _, operand := range e.operands {
+		switch operand.eval(outcome) {
+		case True:
+			return True
+		case Unknown:
+			result = Unknown
+		}
+	}
+	return result
+}
+
+// failedClause is the whole expression, because every operand failed.
+func (e orExpr) failedClause(Outcome) expr {
+	return e
+}
+
+func (e orExpr) checks(add func(string)) {
+	for _, operand := range e.operands {
+		operand.checks(add)
+	}
+}
+
+func (e orExpr) String() string {
+	return joinOperands(e.operands, " OR ", func(expr) bool { return false })
+}
+
+type notExpr struct {
+	operand expr
+}
+
+func (e notExpr) eval(outcome Outcome) Value {
+	switch e.operand.eval(outcome) {
+	case True:
+		return False
+	case False:
+		return True
+	default:
+		return Unknown
+	}
+}
+
+func (e notExpr) failedClause(Outcome) expr {
+	return e
+}
+
+func (e notExpr) checks(add func(string)) {
+	e.operand.checks(add)
+}
+
+func (e notExpr) String() string {
+	return "NOT " + parenthesise(e.operand)
+}
+
+// atLeastExpr passes once n of its operands have passed, and fails once so many have failed that n no longer can.
+type atLeastExpr struct {
+	n        int
+	operands []expr
+}
+
+func (e atLeastExpr) eval(outcome Outcome) Value {
+	passed, unknown := 0, 0
+	for _, operand := range e.operands {
+		switch operand.eval(outcome) {
+		case True:
+			passed++
+		case Unknown:
+			unknown++
+		}
+	}
+
+	switch {
+	case passed >= e.n:
+		return True
+	case passed+unknown < e.n:
+		return False
+	default:
+		return Unknown
+	}
+}
+
+func (e atLeastExpr) failedClause(Outcome) expr {
+	return e
+}
+
+func (e atLeastExpr) checks(add func(string)) {
+	for _, operand := range e.operands {
+		operand.checks(add)
+	}
+}
+
+func (e atLeastExpr) String() string {
+	return fmt.Sprintf("at least %d of (%s)", e.n, joinOperands(e.operands, ", ", func(expr) bool { return false }))
+}
+
+// optionalExpr always passes. Its checks can't fail the policy, but they're still waited for once it has passed so
+// that they can be reported, see OptionalChecks.
+type optionalExpr struct {
+	operand expr
+}
+
+func (e optionalExpr) eval(Outcome) Value {
+	return True
+}
+
+func (e optionalExpr) failedClause(Outcome) expr {
+	return e
+}
+
+func (e optionalExpr) checks(add func(string)) {
+	e.operand.checks(add)
+}
+
+func (e optionalExpr) String() string {
+	return parenthesise(e.operand) + " optional"
+}
+
+func joinOperands(operands []expr, sep string, needsParens func(expr) bool) string {
+	parts := make([]string, 0, len(operands))
+	for _, operand := range operands {
+		if needsParens(operand) {
+			parts = append(parts, "("+operand.String()+")")
+		} else {
+			parts = append(parts, operand.String())
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// parenthesise wraps AND and OR expressions in parentheses, because they bind less tightly than NOT and optional.
+func parenthesise(e expr) string {
+	switch e.(type) {
+	case andExpr, orExpr:
+		return "(" + e.String() + ")"
+	default:
+		return e.String()
+	}
+}
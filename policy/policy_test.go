@@ -0,0 +1,141 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func outcomes(values map[string]Value) Outcome {
+	return func(check string) Value {
+		return values[check]
+	}
+}
+
+func TestParseString(t *testing.T) {
+	tests := []struct {
+		policy, want string
+	}{
+		{policy: "build", want: "build"},
+		{policy: "build and test", want: "build AND test"},
+		{policy: "a OR b AND c", want: "a OR b AND c"},
+		{policy: "(a OR b) AND c", want: "(a OR b) AND c"},
+		{policy: "NOT not a", want: "NOT NOT a"},
+		{policy: "a optional AND b", want: "a optional AND b"},
+		{policy: "(a AND b) optional", want: "(a AND b) optional"},
+		{policy: "at least 2 of (a, b OR c, d)", want: "at least 2 of (a, b OR c, d)"},
+		{policy: `"status with spaces" AND "and"`, want: `"status with spaces" AND "and"`},
+		{policy: `"quote \" inside"`, want: `"quote \" inside"`},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.policy)
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error: %v", tt.policy, err)
+			continue
+		}
+		if got := p.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.policy, got, tt.want)
+		}
+
+		// the canonical form has to parse back to itself.
+		again, err := Parse(p.String())
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error: %v", p.String(), err)
+		} else if again.String() != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", p.String(), again.String(), tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		policy, want string
+	}{
+		{policy: "", want: "the policy is empty"},
+		{policy: "   ", want: "the policy is empty"},
+		{policy: "a AND", want: "expected a check name or ( but the policy ended"},
+		{policy: "a AND OR b", want: `expected a check name or ( at position 7, got "OR"`},
+		{policy: "(a OR b", want: "expected ) but the policy ended"},
+		{policy: "a b", want: `unexpected "b" at position 3`},
+		{policy: `"unterminated`, want: "unterminated quote at position 1"},
+		{policy: "at least x of (a)", want: `expected a number of 1 or more after at least at position 10, got "x"`},
+		{policy: "at least 0 of (a)", want: `expected a number of 1 or more after at least at position 10, got "0"`},
+		{policy: "at least 2 (a, b)", want: "expected of after at least 2"},
+		{policy: "at least 3 of (a, b)", want: "at least 3 of (...) only lists 2"},
+		{policy: "at least 1 of (a b)", want: `expected ) or , at position 18, got "b"`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.policy)
+		if err == nil {
+			t.Errorf("Parse(%q): expected an error", tt.policy)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q): expected an error containing %q, got %q", tt.policy, tt.want, err.Error())
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		policy   string
+		outcomes map[string]Value
+		want     Value
+	}{
+		{policy: "a AND b", outcomes: map[string]Value{"a": True, "b": True}, want: True},
+		{policy: "a AND b", outcomes: map[string]Value{"a": True}, want: Unknown},
+		{policy: "a AND b", outcomes: map[string]Value{"b": False}, want: False},
+		{policy: "a OR b", outcomes: map[string]Value{"b": True}, want: True},
+		{policy: "a OR b", outcomes: map[string]Value{"a": False}, want: Unknown},
+		{policy: "a OR b", outcomes: map[string]Value{"a": False, "b": False}, want: False},
+		{policy: "NOT a", outcomes: map[string]Value{"a": False}, want: True},
+		{policy: "NOT a", outcomes: map[string]Value{}, want: Unknown},
+		{policy: "a AND b optional", outcomes: map[string]Value{"a": True, "b": False}, want: True},
+		{policy: "at least 2 of (a, b, c)", outcomes: map[string]Value{"a": True, "c": True}, want: True},
+		{policy: "at least 2 of (a, b, c)", outcomes: map[string]Value{"a": True, "b": False}, want: Unknown},
+		{policy: "at least 2 of (a, b, c)", outcomes: map[string]Value{"a": False, "b": False}, want: False},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.policy)
+		if err != nil {
+			t.Fatalf("Parse(%q): unexpected error: %v", tt.policy, err)
+		}
+		if got := p.Eval(outcomes(tt.outcomes)); got != tt.want {
+			t.Errorf("%q with %v = %s, want %s", tt.policy, tt.outcomes, got, tt.want)
+		}
+	}
+}
+
+func TestFailedClause(t *testing.T) {
+	p, err := Parse("build AND (e2e-chrome OR e2e-firefox) AND lint optional")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	outcome := outcomes(map[string]Value{"build": True, "e2e-chrome": False, "e2e-firefox": False, "lint": False})
+	if got := p.Eval(outcome); got != False {
+		t.Fatalf("expected the policy to fail, got %s", got)
+	}
+
+	clause, failed := p.FailedClause(outcome)
+	if want := "e2e-chrome OR e2e-firefox"; clause != want {
+		t.Errorf("expected the clause %q, got %q", want, clause)
+	}
+	if want := []string{"e2e-chrome", "e2e-firefox"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("expected the failed checks %v, got %v", want, failed)
+	}
+}
+
+func TestChecks(t *testing.T) {
+	p, err := Parse(`a AND (b OR a) AND at least 1 of (c, d optional) AND NOT "e f" optional`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := []string{"a", "b", "c", "d", "e f"}; !reflect.DeepEqual(p.Checks(), want) {
+		t.Errorf("expected the checks %v, got %v", want, p.Checks())
+	}
+	if want := []string{"d", "e f"}; !reflect.DeepEqual(p.OptionalChecks(), want) {
+		t.Errorf("expected the optional checks %v, got %v", want, p.OptionalChecks())
+	}
+}
@@ -0,0 +1,79 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const placeholder = "***"
+
+// Redactor masks secrets and anything matching user supplied patterns before text leaves the process.
+type Redactor struct {
+	secrets  []string
+	patterns []*regexp.Regexp
+}
+
+// New creates a Redactor. Empty secrets are ignored and each pattern must be a valid regular expression.
+func New(secrets []string, patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, secret := range secrets {
+		if secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q - %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+// With returns a copy of the Redactor that also masks the given secrets.
+func (r *Redactor) With(secrets ...string) *Redactor {
+	c := &Redactor{}
+	if r != nil {
+		c.secrets = append(c.secrets, r.secrets...)
+		c.patterns = r.patterns
+	}
+
+	for _, secret := range secrets {
+		if secret != "" {
+			c.secrets = append(c.secrets, secret)
+		}
+	}
+	return c
+}
+
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, placeholder)
+	}
+
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, placeholder)
+	}
+
+	return s
+}
+
+// DefaultPatterns match credentials that should never be logged or posted, even if they weren't passed in as secrets.
+var DefaultPatterns = []string{
+	`https://hooks\.slack\.com/[A-Za-z0-9/_-]+`,
+	`\bgh[pousr]_[A-Za-z0-9]{20,}\b`,
+	`\bgithub_pat_[A-Za-z0-9_]{20,}\b`,
+	`\bxox[abprs]-[A-Za-z0-9-]+\b`,
+}
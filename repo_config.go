@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+
+	"github.com/tamj0rd2/pipeline-status-action/actions"
+	"github.com/tamj0rd2/pipeline-status-action/configfile"
+	"github.com/tamj0rd2/pipeline-status-action/github"
+	"github.com/tamj0rd2/pipeline-status-action/redact"
+	"github.com/tamj0rd2/pipeline-status-action/slack"
+)
+
+// loadRepoConfig fetches the config file from the repository being checked, at the commit being checked, and passes
+// it to apply, which layers it over the central config. It returns false if there's nothing to layer over the central
+// config, either because the repository has no file or because it couldn't be used. A file that's invalid, or that
+// apply rejects, is reported to the repository's own channel rather than failing the run, so that one repository's
+// mistake doesn't stop it being checked at all.
+func loadRepoConfig(ctx context.Context, service *github.Service, config config, redactor *redact.Redactor, apply func(configfile.File) error) bool {
+	if config.repoConfigPath == "" {
+		return false
+	}
+
+	content, found, err := service.GetFileContent(ctx, config.owner, config.repoName, config.sha, config.repoConfigPath)
+	if err != nil {
+		slog.Warn("failed to get the repository's config file, using the central config", "path", config.repoConfigPath, "error", err)
+		return false
+	}
+	if !found {
+		slog.Info("the repository has no config file, using the central config", "path", config.repoConfigPath)
+		return false
+	}
+
+	file, err := configfile.Parse(bytes.NewReader(content))
+	if err == nil {
+		err = configfile.CheckRepoNotifiers(config.file, file)
+	}
+	if err == nil {
+		err = configfile.Merge(config.file, file).CheckReferences()
+	}
+	if err == nil {
+		err = apply(file)
+	}
+	if err != nil {
+		slog.Error("the repository's config file is invalid, using the central config", "path", config.repoConfigPath, "error", err)
+		reportInvalidRepoConfig(ctx, service, config, redactor, content, err)
+		return false
+	}
+
+	slog.Info("loaded the repository's config file", "path", config.repoConfigPath)
+	return true
+}
+
+// reportInvalidRepoConfig alerts the default notifier of the invalid file if it can still be read from it, and the
+// central default notifier otherwise.
+func reportInvalidRepoConfig(ctx context.Context, service *github.Service, config config, redactor *redact.Redactor, content []byte, err error) {
+	webhookURL := config.slackWebhookURL
+	if notifier, ok := configfile.RecoverNotifier(config.file, content, configfile.DefaultNotifier); ok {
+		webhookURL = notifier.URL()
+		actions.AddMask(webhookURL)
+		redactor = redactor.With(webhookURL)
+	}
+
+	if webhookURL == "" {
+		slog.Warn("not reporting the invalid config file because there's no notifier to report it to")
+		return
+	}
+
+	alert := slack.ConfigAlert{
+		Commit:       getCommitInfo(ctx, service, config),
+		Path:         config.repoConfigPath,
+		ErrorMessage: err.Error(),
+	}
+	if notifyErr := slack.AlertThatConfigIsInvalid(ctx, webhookURL, redactor, alert); notifyErr != nil {
+		slog.Error("failed to report the invalid config file", "error", notifyErr)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/github"
+	"github.com/tamj0rd2/pipeline-status-action/policy"
+	"github.com/tamj0rd2/pipeline-status-action/report"
+)
+
+var errorCategories = map[int]string{
+	exitChecksFailed:  "checks_failed",
+	exitTimedOut:      "timed_out",
+	exitChecksMissing: "checks_missing",
+	exitAPIError:      "api_error",
+	exitAuthError:     "auth_error",
+	exitNotifierError: "notifier_error",
+	exitInvalidUsage:  "invalid_usage",
+	exitError:         "error",
+}
+
+func errorCategory(err error) string {
+	return errorCategories[exitCode(err)]
+}
+
+func writeReport(config config, commit github.CommitInfo, statuses []github.Status, err error, apiStats github.APIStats, startedAt time.Time) error {
+	r := report.Report{
+		Config: report.Config{
+			Repository:     config.owner + "/" + config.repoName,
+			SHA:            config.sha,
+			CheckNames:     config.statusNames,
+			TimeoutSeconds: int(config.timeout.Seconds()),
+			RetryPolicies:  config.retryPolicies,
+			CheckTimeouts:  report.NewCheckTimeouts(config.checkTimeouts),
+			Mode:           config.mode,
+			Policy:         policyText(config.policy),
+		},
+		Commit:        commit,
+		Checks:        report.NewChecks(statuses),
+		Stages:        github.StageResults(statuses, config.stages),
+		APIStats:      apiStats,
+		Outcome:       result(err),
+		ErrorCategory: errorCategory(err),
+		StartedAt:     startedAt.UTC(),
+		FinishedAt:    time.Now().UTC(),
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	return report.WriteJSON(config.reportFile, r)
+}
+
+// policyText returns the policy in its canonical form, or nothing if there isn't one.
+func policyText(p *policy.Policy) string {
+	if p == nil {
+		return ""
+	}
+	return p.String()
+}
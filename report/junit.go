@@ -0,0 +1,96 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+
+	"github.com/tamj0rd2/pipeline-status-action/github"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the tracked checks as a JUnit XML test suite, so that they show up in dashboards alongside
+// normal test results. Failed checks become failures, and checks that never reported or didn't finish become errors
+// that say which deadline, if any, they missed.
+func WriteJUnit(path string, suiteName string, statuses []github.Status) error {
+	suite := junitTestSuite{Name: suiteName, Tests: len(statuses)}
+
+	var totalSeconds float64
+	for _, status := range statuses {
+		testCase := junitTestCase{
+			Name:      status.Name,
+			ClassName: suiteName,
+			Time:      seconds(status.Duration().Seconds()),
+		}
+		totalSeconds += status.Duration().Seconds()
+
+		switch status.State() {
+		case github.StateFailure:
+			suite.Failures++
+			testCase.Failure = &junitProblem{Message: status.Description, Type: github.StateFailure, Body: status.Url}
+		case github.StatePending:
+			suite.Errors++
+			testCase.Error = &junitProblem{Message: unfinishedMessage(status, "still running when the wait ended"), Type: github.StatePending, Body: status.Url}
+		case github.StateMissing:
+			suite.Errors++
+			testCase.Error = &junitProblem{Message: unfinishedMessage(status, "was never reported"), Type: github.StateMissing}
+		case github.StateSkipped:
+			testCase.Skipped = &junitSkipped{Message: "not waited for because an earlier stage failed"}
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	suite.Time = seconds(totalSeconds)
+
+	data, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0o644)
+}
+
+// unfinishedMessage says which deadline a check that didn't finish missed, or otherwise gives the fallback, since the
+// wait can end with checks still going without any of them having run out of time.
+func unfinishedMessage(status github.Status, fallback string) string {
+	if text := status.MissedDeadlineText(); text != "" {
+		return text
+	}
+	return fallback
+}
+
+func seconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
@@ -0,0 +1,122 @@
+// Package report writes machine readable results of a run, so other tooling doesn't need to scrape the logs.
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/github"
+)
+
+// Report is the full result of waiting for a commit's checks.
+type Report struct {
+	Config        Config               `json:"config"`
+	Commit        github.CommitInfo    `json:"commit"`
+	Checks        []Check              `json:"checks"`
+	Stages        []github.StageResult `json:"stages,omitempty"`
+	APIStats      github.APIStats      `json:"apiStats"`
+	Outcome       string               `json:"outcome"`
+	ErrorCategory string               `json:"errorCategory,omitempty"`
+	Error         string               `json:"error,omitempty"`
+	StartedAt     time.Time            `json:"startedAt"`
+	FinishedAt    time.Time            `json:"finishedAt"`
+}
+
+// Config is the configuration that the run used, minus any secrets.
+type Config struct {
+	Repository     string               `json:"repository"`
+	SHA            string               `json:"sha"`
+	CheckNames     []string             `json:"checkNames"`
+	TimeoutSeconds int                  `json:"timeoutSeconds"`
+	RetryPolicies  []github.RetryPolicy `json:"retryPolicies,omitempty"`
+	CheckTimeouts  []CheckTimeouts      `json:"checkTimeouts,omitempty"`
+	Mode           string               `json:"mode"`
+	Policy         string               `json:"policy,omitempty"`
+}
+
+// CheckTimeouts are the deadlines configured for the checks matching a pattern. Zero means there isn't one.
+type CheckTimeouts struct {
+	Pattern                 string  `json:"pattern"`
+	AppearWithinSeconds     float64 `json:"appearWithinSeconds"`
+	FinishWithinSeconds     float64 `json:"finishWithinSeconds"`
+	ExpectedDurationSeconds float64 `json:"expectedDurationSeconds"`
+}
+
+func NewCheckTimeouts(timeouts []github.CheckTimeouts) []CheckTimeouts {
+	var converted []CheckTimeouts
+	for _, t := range timeouts {
+		converted = append(converted, CheckTimeouts{
+			Pattern:                 t.Pattern,
+			AppearWithinSeconds:     t.AppearWithin.Seconds(),
+			FinishWithinSeconds:     t.FinishWithin.Seconds(),
+			ExpectedDurationSeconds: t.ExpectedDuration.Seconds(),
+		})
+	}
+	return converted
+}
+
+type Check struct {
+	Name        string              `json:"name"`
+	State       string              `json:"state"`
+	URL         string              `json:"url,omitempty"`
+	Description string              `json:"description,omitempty"`
+	StartedAt   *time.Time          `json:"startedAt,omitempty"`
+	CompletedAt *time.Time          `json:"completedAt,omitempty"`
+	FirstSeenAt *time.Time          `json:"firstSeenAt,omitempty"`
+	Retries     int                 `json:"retries"`
+	Severity    string              `json:"severity"`
+	Stage       string              `json:"stage,omitempty"`
+	Transitions []github.Transition `json:"transitions"`
+	// MissedDeadline is appear, finish or wait if the check was given up on for taking too long.
+	MissedDeadline             string  `json:"missedDeadline,omitempty"`
+	MissedDeadlineAfterSeconds float64 `json:"missedDeadlineAfterSeconds,omitempty"`
+	Overran                    bool    `json:"overran,omitempty"`
+	// Suggestions are reported checks with similar names, for checks that were never reported.
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+func NewChecks(statuses []github.Status) []Check {
+	checks := make([]Check, 0, len(statuses))
+	for _, status := range statuses {
+		transitions := status.Transitions
+		if transitions == nil {
+			transitions = []github.Transition{}
+		}
+
+		checks = append(checks, Check{
+			Name:        status.Name,
+			State:       status.State(),
+			URL:         status.Url,
+			Description: status.Description,
+			StartedAt:   optionalTime(status.StartedAt),
+			CompletedAt: optionalTime(status.CompletedAt),
+			FirstSeenAt: optionalTime(status.FirstSeenAt),
+			Retries:     status.Retries,
+			Severity:    status.Severity,
+			Stage:       status.Stage,
+			Transitions: transitions,
+
+			MissedDeadline:             status.MissedDeadline,
+			MissedDeadlineAfterSeconds: status.MissedDeadlineAfter.Seconds(),
+			Overran:                    status.Overran(),
+			Suggestions:                status.Suggestions,
+		})
+	}
+	return checks
+}
+
+func WriteJSON(path string, report Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
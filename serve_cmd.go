@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/configfile"
+	"github.com/tamj0rd2/pipeline-status-action/github"
+	"github.com/tamj0rd2/pipeline-status-action/metrics"
+	"github.com/tamj0rd2/pipeline-status-action/policy"
+	"github.com/tamj0rd2/pipeline-status-action/redact"
+)
+
+// waitRequest is the body of a request to start waiting for the checks on a commit.
+type waitRequest struct {
+	Repository     string   `json:"repository"`
+	SHA            string   `json:"sha"`
+	CheckNames     []string `json:"checkNames"`
+	TimeoutMinutes int      `json:"timeoutMinutes"`
+	Mode           string   `json:"mode"`
+	Policy         string   `json:"policy"`
+}
+
+// server waits for checks on request. Every wait shares its GitHub client, so API metrics cover all of them. Its
+// flags are defaults, which config files and then requests override.
+type server struct {
+	ctx            context.Context
+	service        *github.Service
+	registry       *metrics.Registry
+	redactor       *redact.Redactor
+	defaults       config
+	maxTimeout     time.Duration
+	defaultTimeout time.Duration
+	// authToken is the bearer token that requests to start a wait must send.
+	authToken string
+	// waits has room for as many waits as can run at once.
+	waits chan struct{}
+}
+
+func runServe(args []string) error {
+	flags := newFlagSet("serve")
+	log := addLogFlags(flags)
+	addr := flags.String("addr", ":8080", "The address to listen on")
+	token := flags.String("token", "", "GitHub token. Defaults to $GITHUB_TOKEN")
+	authToken := flags.String("authToken", "", "A shared secret that requests to /wait must send as a bearer token. Defaults to $PIPELINE_STATUS_AUTH_TOKEN")
+	maxConcurrentWaits := flags.Int("maxConcurrentWaits", 20, "The most waits that can run at once. Requests beyond that are turned away")
+	slackWebhookURL := flags.String("slackWebhookURL", "", "The slack webhook URL to send alerts to. Alerts aren't sent if it's empty")
+	redactPatterns := flags.String("redactPatterns", "", "A newline separated list of regular expressions to redact from anything that gets posted")
+	timeoutMinutes := flags.Int("timeoutMinutes", 60, "The number of minutes a wait times out after, unless a config file or the request says otherwise")
+	maxTimeoutMinutes := flags.Int("maxTimeoutMinutes", 180, "The most minutes a request can ask to wait for")
+	logExcerptLines := flags.Int("logExcerptLines", 20, "The number of job log lines to include in alerts for failed GitHub Actions jobs. 0 disables it")
+	maxAnnotations := flags.Int("maxAnnotations", 5, "The maximum number of check run annotations to include per failed check. 0 disables it")
+	configPath := flags.String(configFlag, "", "A YAML config file with defaults for every repository. Defaults to "+configfile.DefaultPath+" if it exists")
+	repoConfigPath := flags.String("repoConfig", configfile.DefaultPath, "A config file to read from each repository being checked, at the commit being checked. Empty disables it")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	centralFile, _, err := configfile.Load(*configPath)
+	if err != nil {
+		return usageErrorf("failed to load config file - %w", err)
+	}
+
+	*token = orEnv(*token, "GITHUB_TOKEN")
+	if *token == "" {
+		return usageErrorf("token is required")
+	}
+
+	// anyone who can reach the server could otherwise make it spend the token's API quota and post alerts.
+	*authToken = orEnv(*authToken, "PIPELINE_STATUS_AUTH_TOKEN")
+	if *authToken == "" {
+		return usageErrorf("authToken is required")
+	}
+
+	if *maxConcurrentWaits <= 0 {
+		return usageErrorf("maxConcurrentWaits must be more than 0")
+	}
+
+	if *timeoutMinutes <= 0 || *maxTimeoutMinutes < *timeoutMinutes {
+		return usageErrorf("timeoutMinutes must be more than 0 and no more than maxTimeoutMinutes")
+	}
+
+	if *logExcerptLines < 0 {
+		return usageErrorf("logExcerptLines must not be negative")
+	}
+
+	if *maxAnnotations < 0 {
+		return usageErrorf("maxAnnotations must not be negative")
+	}
+
+	patterns := strings.Split(*redactPatterns, "\n")
+	redactor, err := log.setDefaultLogger([]string{*token, *authToken, *slackWebhookURL}, patterns)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	s := &server{
+		ctx:      ctx,
+		service:  github.NewService(ctx, *token),
+		registry: metrics.NewRegistry(),
+		redactor: redactor,
+		defaults: config{
+			token:           *token,
+			slackWebhookURL: *slackWebhookURL,
+			logExcerptLines: *logExcerptLines,
+			maxAnnotations:  *maxAnnotations,
+			maxFailedTests:  10,
+			mode:            github.ModeFailFast,
+			redactPatterns:  patterns,
+			repoConfigPath:  *repoConfigPath,
+			file:            centralFile,
+		},
+		defaultTimeout: time.Minute * time.Duration(*timeoutMinutes),
+		maxTimeout:     time.Minute * time.Duration(*maxTimeoutMinutes),
+		authToken:      *authToken,
+		waits:          make(chan struct{}, *maxConcurrentWaits),
+	}
+	registerAPIMetrics(s.registry, s.service)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("/metrics", s.registry.Handler())
+	mux.HandleFunc("/wait", s.handleWait)
+
+	slog.Info("listening", "addr", *addr)
+	httpServer := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return httpServer.ListenAndServe()
+}
+
+// handleWait starts waiting in the background and responds straight away, because a wait can take hours. The outcome
+// is reported the same way as it is by the wait command.
+func (s *server) handleWait(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req waitRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body - "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target, err := s.target(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var config config
+	if !loadRepoConfig(r.Context(), s.service, target, s.redactor, func(file configfile.File) (err error) {
+		config, err = s.config(target, req, configfile.Merge(target.file, file))
+		return err
+	}) {
+		if config, err = s.config(target, req, target.file); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	select {
+	case s.waits <- struct{}{}:
+	default:
+		http.Error(w, "too many waits are already running", http.StatusTooManyRequests)
+		return
+	}
+
+	// the config files can name notifiers whose webhook URLs the server's redactor doesn't know about yet.
+	redactor := s.redactor.With(config.secrets()...)
+	go func() {
+		defer func() { <-s.waits }()
+		if err := waitAndNotify(s.ctx, s.service, config, redactor, s.registry); err != nil {
+			slog.Error("wait failed", "owner", config.owner, "repo", config.repoName, "sha", config.sha, "error", err, "exitCode", exitCode(err))
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// authorized reports whether the request sent the server's auth token as a bearer token.
+func (s *server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.authToken)) == 1
+}
+
+// target returns the server's defaults for the commit the request is about.
+func (s *server) target(req waitRequest) (config, error) {
+	owner, repoName, err := splitRepository(req.Repository)
+	if err != nil {
+		return config{}, err
+	}
+
+	if req.SHA == "" {
+		return config{}, usageErrorf("sha is required")
+	}
+
+	config := s.defaults
+	config.owner = owner
+	config.repoName = repoName
+	config.sha = req.SHA
+	return config, nil
+}
+
+// config layers the merged config files and then the request over the server's defaults for the target commit.
+func (s *server) config(target config, req waitRequest, file configfile.File) (config, error) {
+	config := target
+	config.file = file
+
+	config.statusNames = file.CheckNames()
+	if len(req.CheckNames) > 0 {
+		config.statusNames = nil
+		for _, name := range req.CheckNames {
+			if name = strings.TrimSpace(name); name != "" {
+				config.statusNames = append(config.statusNames, name)
+			}
+		}
+	}
+
+	policyText := file.Policy
+	if req.Policy != "" {
+		policyText = req.Policy
+	}
+	if policyText != "" {
+		parsed, err := policy.Parse(policyText)
+		if err != nil {
+			return config, usageErrorf("invalid policy - %w", err)
+		}
+		config.policy = &parsed
+	}
+
+	if len(config.statusNames) == 0 && config.policy == nil {
+		return config, usageErrorf("checkNames or policy is required, either in the request or a config file")
+	}
+
+	config.timeout = s.defaultTimeout
+	if file.Timeouts.WaitMinutes != 0 {
+		config.timeout = time.Minute * time.Duration(file.Timeouts.WaitMinutes)
+	}
+	if req.TimeoutMinutes != 0 {
+		config.timeout = time.Minute * time.Duration(req.TimeoutMinutes)
+	}
+	if config.timeout <= 0 || config.timeout > s.maxTimeout {
+		return config, usageErrorf("timeoutMinutes must be more than 0 and no more than %d", int(s.maxTimeout.Minutes()))
+	}
+
+	for _, pattern := range file.Patterns {
+		if pattern.Retries != nil {
+			config.retryPolicies = append(config.retryPolicies, github.RetryPolicy{Pattern: pattern.Match, MaxRetries: *pattern.Retries})
+		}
+	}
+	config.checkTimeouts = fileCheckTimeouts(file)
+
+	severities, err := parseSeverities(strings.Join(fileSeverities(file), ","))
+	if err != nil {
+		return config, err
+	}
+	config.severities = severities
+	if err := checkSomeRequired(config.statusNames, config.severities, config.policy); err != nil {
+		return config, err
+	}
+
+	stages, err := parseStages(fileStages(file))
+	if err != nil {
+		return config, err
+	}
+	config.stages = stages
+	if err := checkStagesMatch(config.stages, config.statusNames, config.policy); err != nil {
+		return config, err
+	}
+
+	if file.Mode != "" {
+		config.mode = file.Mode
+	}
+	if req.Mode != "" {
+		config.mode = req.Mode
+	}
+	if config.mode != github.ModeFailFast && config.mode != github.ModeCollectAll {
+		return config, usageErrorf("mode must be %s or %s, got %q", github.ModeFailFast, github.ModeCollectAll, config.mode)
+	}
+	config.earlyAlerts = file.EarlyAlerts != nil && *file.EarlyAlerts && config.mode == github.ModeCollectAll
+
+	if notifier, ok := file.Notifiers[configfile.DefaultNotifier]; ok {
+		config.slackWebhookURL = notifier.URL()
+	}
+	config.notifiers = fileNotifiers(file)
+	config.routes = file.Routes
+
+	return config, nil
+}
@@ -1,75 +1,208 @@
 package slack
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/tamj0rd2/pipeline-status-action/github"
+	"github.com/tamj0rd2/pipeline-status-action/redact"
 )
 
-func AlertThatStatusFailed(
-	ctx context.Context,
-	webhookURL string,
-	commitURL, commitAuthor, commitMessage string,
-	errorMessage string,
-	failedStatuses []github.Status,
-) error {
+// Alert is everything that gets included in a failure notification.
+type Alert struct {
+	Commit         github.CommitInfo
+	ErrorMessage   string
+	FailedStatuses []github.Status
+	// MaxFailedTests limits how many failed tests are listed per status.
+	MaxFailedTests int
+	// KnownFlaky holds the names of checks that have previously failed and then passed on the same commit.
+	KnownFlaky map[string]bool
+	// Warnings are checks that failed without failing the gate, listed after the failed statuses.
+	Warnings []github.Status
+	// Stages are shown as a progress bar, if the checks are split into stages.
+	Stages []github.StageResult
+	// Title is the header of the alert. It defaults to saying that the commit statuses failed.
+	Title string
+}
+
+// NotifierError is returned when a notification couldn't be sent.
+type NotifierError struct {
+	Err error
+}
+
+func (e NotifierError) Error() string {
+	return fmt.Sprintf("failed to send slack notification - %s", e.Err)
+}
+
+func (e NotifierError) Unwrap() error {
+	return e.Err
+}
+
+func AlertThatStatusFailed(ctx context.Context, webhookURL string, redactor *redact.Redactor, alert Alert) error {
+	if err := sendAlert(ctx, webhookURL, redactor, alert); err != nil {
+		return NotifierError{Err: err}
+	}
+	return nil
+}
+
+func sendAlert(ctx context.Context, webhookURL string, redactor *redact.Redactor, alert Alert) error {
 	var failedStatusMsg []string
-	for _, status := range failedStatuses {
-		failedStatusMsg = append(failedStatusMsg, fmt.Sprintf("<%v|%s>", status.Url, status.Name))
-	}
-
-	errorBody := fmt.Sprintf("*Error*: %s\n*Failed statuses*: %s", errorMessage, strings.Join(failedStatusMsg, ", "))
-	commitDetails := fmt.Sprintf("*Commit author*: %s\n*Commit message*: %s", commitAuthor, commitMessage)
-
-	requestBody := fmt.Sprintf(`{
-		"blocks": [
-			{
-				"type": "header",
-				"text": {
-					"type": "plain_text",
-					"text": ":x: Commit statuses failed",
-					"emoji": true
-				}
-			},
-			{
-				"type": "section",
-				"text": {
-					"type": "mrkdwn",
-					"text": "%s",
-				}
-			},
-			{
-				"type": "divider",
-			},
-			{
-				"type": "section",
-				"text": {
-					"type": "mrkdwn",
-					"text": "%s",
-				}
-			},
-			{
-				"type": "actions",
-				"elements": [
-					{
-						"type": "button",
-						"text": {
-							"type": "plain_text",
-							"text": "Github commit"
-						},
-						"url": "%s"
-					}
-				]
-			}
-		]
-	}`, errorBody, commitDetails, commitURL)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, strings.NewReader(requestBody))
+	for _, status := range alert.FailedStatuses {
+		failedStatusMsg = append(failedStatusMsg, statusText(status, alert))
+	}
+
+	errorBody := fmt.Sprintf("*Error*: %s\n*Failed statuses*: %s", alert.ErrorMessage, strings.Join(failedStatusMsg, ", "))
+	if len(alert.Warnings) > 0 {
+		var warningMsg []string
+		for _, status := range alert.Warnings {
+			warningMsg = append(warningMsg, statusText(status, alert))
+		}
+		errorBody += fmt.Sprintf("\n*Non-blocking failures*: %s", strings.Join(warningMsg, ", "))
+	}
+
+	title := alert.Title
+	if title == "" {
+		title = ":x: Commit statuses failed"
+	}
+
+	blocks := []block{
+		{Type: "header", Text: plainText(title)},
+		section(errorBody),
+	}
+
+	if len(alert.Stages) > 0 {
+		blocks = append(blocks, section(stagesText(alert.Stages)))
+	}
+
+	var details []block
+	for _, status := range alert.FailedStatuses {
+		if len(status.Annotations) > 0 {
+			details = append(details, section(annotationsText(status)))
+		}
+
+		if len(status.FailedTests) > 0 {
+			details = append(details, section(failedTestsText(status, alert.MaxFailedTests)))
+		}
+
+		if status.LogExcerpt != "" {
+			details = append(details, section(logExcerptText(status)))
+		}
+	}
+
+	footer := []block{
+		{Type: "divider"},
+		section(commitDetailsText(alert.Commit)),
+		{Type: "actions", Elements: []element{{Type: "button", Text: plainText("Github commit"), URL: alert.Commit.HTMLURL}}},
+	}
+
+	// in collect-all mode there can be too many details for one message, so the rest are left to the job's output.
+	if room := maxBlocks - len(blocks) - len(footer); len(details) > room {
+		shown := room - 1
+		details = append(details[:shown], section(fmt.Sprintf("_...and %d more, see the job's output_", len(details)-shown)))
+	}
+
+	blocks = append(blocks, details...)
+	blocks = append(blocks, footer...)
+	return post(ctx, webhookURL, redactor, blocks)
+}
+
+// statusText describes a check that didn't succeed, linking to it.
+func statusText(status github.Status, alert Alert) string {
+	msg := fmt.Sprintf("<%v|%s>", status.Url, status.Name)
+	if alert.KnownFlaky[status.Name] {
+		msg += " :warning: known flaky"
+	}
+	if status.Retries > 0 {
+		msg += fmt.Sprintf(" (still failing after %d %s)", status.Retries, plural(status.Retries, "retry", "retries"))
+	}
+	switch status.State() {
+	case github.StateMissing:
+		msg += " (never reported"
+		if didYouMean := status.DidYouMean(); didYouMean != "" {
+			msg += ", " + didYouMean
+		}
+		msg += ")"
+	case github.StatePending:
+		msg += " (still running)"
+	}
+	// running out of time overall is already covered by the state.
+	if deadline := status.MissedDeadlineText(); deadline != "" && status.MissedDeadline != github.DeadlineWait {
+		msg += " (" + deadline + ")"
+	}
+	if status.Overran() {
+		msg += fmt.Sprintf(" (ran for %s, expected %s)", status.Duration().Round(time.Second), status.ExpectedDuration)
+	}
+	return msg
+}
+
+var stageEmoji = map[string]string{
+	github.StateSuccess: ":white_check_mark:",
+	github.StateFailure: ":x:",
+	github.StatePending: ":hourglass_flowing_sand:",
+	github.StateMissing: ":white_circle:",
+	github.StateSkipped: ":fast_forward:",
+}
+
+// stagesText shows how far the pipeline got as a bar of the stages that passed, followed by the state of each stage.
+func stagesText(stages []github.StageResult) string {
+	var bar strings.Builder
+	var steps []string
+	passed := 0
+	for _, stage := range stages {
+		if stage.State == github.StateSuccess {
+			passed++
+			bar.WriteString("■")
+		} else {
+			bar.WriteString("□")
+		}
+		steps = append(steps, stageEmoji[stage.State]+" "+stage.Name)
+	}
+
+	return fmt.Sprintf("*Stages*: `%s` %d/%d passed\n%s", bar.String(), passed, len(stages), strings.Join(steps, " → "))
+}
+
+// ConfigAlert is sent when a repository's own config file can't be used.
+type ConfigAlert struct {
+	Commit       github.CommitInfo
+	Path         string
+	ErrorMessage string
+}
+
+func AlertThatConfigIsInvalid(ctx context.Context, webhookURL string, redactor *redact.Redactor, alert ConfigAlert) error {
+	blocks := []block{
+		{Type: "header", Text: plainText(":warning: Pipeline status config is invalid")},
+		section(fmt.Sprintf("`%s` couldn't be used, so the central config was used instead.\n*Error*: %s", alert.Path, truncate(alert.ErrorMessage, maxExcerptLength))),
+		{Type: "divider"},
+		section(commitDetailsText(alert.Commit)),
+		{Type: "actions", Elements: []element{{Type: "button", Text: plainText("Github commit"), URL: alert.Commit.HTMLURL}}},
+	}
+
+	if err := post(ctx, webhookURL, redactor, blocks); err != nil {
+		return NotifierError{Err: err}
+	}
+	return nil
+}
+
+func post(ctx context.Context, webhookURL string, redactor *redact.Redactor, blocks []block) error {
+	for _, b := range blocks {
+		if b.Text != nil {
+			b.Text.Text = redactor.Redact(b.Text.Text)
+		}
+	}
+
+	requestBody, err := json.Marshal(message{Blocks: blocks})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(requestBody))
 	if err != nil {
 		return err
 	}
@@ -85,11 +218,126 @@ func AlertThatStatusFailed(
 	case http.StatusOK:
 		return nil
 	default:
-		log.Println("Request body:", requestBody)
-
-		body, _ := io.ReadAll(res.Body)
-		log.Println("Response body:", string(body))
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		slog.DebugContext(ctx, "slack rejected the alert", "statusCode", res.StatusCode, "responseBody", string(body), "requestBytes", len(requestBody))
 
 		return fmt.Errorf("unexpected status code: %d", res.StatusCode)
 	}
 }
+
+type message struct {
+	Blocks []block `json:"blocks"`
+}
+
+type block struct {
+	Type     string    `json:"type"`
+	Text     *text     `json:"text,omitempty"`
+	Elements []element `json:"elements,omitempty"`
+}
+
+type element struct {
+	Type string `json:"type"`
+	Text *text  `json:"text,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type text struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+func plainText(s string) *text {
+	return &text{Type: "plain_text", Text: s, Emoji: true}
+}
+
+func markdown(s string) *text {
+	return &text{Type: "mrkdwn", Text: s}
+}
+
+// section is a block of markdown, cut short if it's longer than slack allows.
+func section(s string) block {
+	return block{Type: "section", Text: markdown(truncate(s, maxSectionLength-len("...")))}
+}
+
+const (
+	// slack rejects messages with more than 50 blocks, and sections with more than 3000 characters of text.
+	maxBlocks        = 50
+	maxSectionLength = 3000
+
+	maxSubjectLength        = 45
+	maxFailureMessageLength = 150
+	// leave some room under maxSectionLength for the heading.
+	maxExcerptLength = 2500
+)
+
+func commitDetailsText(commit github.CommitInfo) string {
+	return fmt.Sprintf("*Commit author*: %s\n*Commit message*: %s", orUnknown(commit.Author.Name), orUnknown(truncate(commit.Subject, maxSubjectLength)))
+}
+
+func logExcerptText(status github.Status) string {
+	heading := fmt.Sprintf("*<%s|%s>* logs", status.Url, status.Name)
+	if status.FailedStep != "" {
+		heading = fmt.Sprintf("*<%s|%s>* failed at step `%s`", status.Url, status.Name, status.FailedStep)
+	}
+
+	excerpt := status.LogExcerpt
+	if runes := []rune(excerpt); len(runes) > maxExcerptLength {
+		excerpt = "..." + string(runes[len(runes)-maxExcerptLength:])
+	}
+
+	return fmt.Sprintf("%s\n```%s```", heading, strings.ReplaceAll(excerpt, "```", "'''"))
+}
+
+func annotationsText(status github.Status) string {
+	lines := []string{fmt.Sprintf("*<%s|%s>* annotations:", status.Url, status.Name)}
+	for _, annotation := range status.Annotations {
+		lines = append(lines, fmt.Sprintf("• <%s|%s:%d> %s", annotation.URL, annotation.Path, annotation.StartLine, truncate(firstLine(annotation.Message), maxFailureMessageLength)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func failedTestsText(status github.Status, max int) string {
+	lines := []string{fmt.Sprintf("*<%s|%s>* failed tests:", status.Url, status.Name)}
+	for i, failure := range status.FailedTests {
+		if i == max {
+			lines = append(lines, fmt.Sprintf("...and %d more", len(status.FailedTests)-max))
+			break
+		}
+
+		line := fmt.Sprintf("• `%s`", failure.FullName())
+		if failure.Message != "" {
+			line += " - " + truncate(failure.Message, maxFailureMessageLength)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncate shortens s to at most max runes, adding an ellipsis when anything was cut off.
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "..."
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/tamj0rd2/pipeline-status-action/github"
+	"github.com/tamj0rd2/pipeline-status-action/report"
+)
+
+func runStatus(args []string) error {
+	flags := newFlagSet("status")
+	target := addTargetFlags(flags)
+	checkNames := flags.String("checkNames", "", "A comma separated list of the checks to show")
+	format := flags.String("format", formatTable, "The output format, table or json")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	token, owner, repoName, sha, err := target.parse()
+	if err != nil {
+		return err
+	}
+
+	checks := splitList(*checkNames)
+	if len(checks) == 0 {
+		return usageErrorf("checkNames is required")
+	}
+
+	if *format != formatTable && *format != formatJSON {
+		return usageErrorf("format must be %s or %s", formatTable, formatJSON)
+	}
+
+	ctx := context.Background()
+	statuses, err := github.NewService(ctx, token).Snapshot(ctx, owner, repoName, sha, checks)
+	if err != nil {
+		return err
+	}
+
+	if *format == formatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report.NewChecks(statuses))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSTATE\tDURATION\tURL")
+	for _, status := range statuses {
+		duration := "-"
+		if status.Finished {
+			duration = roundDuration(status.Duration()).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Name, status.State(), duration, status.Url)
+	}
+	return w.Flush()
+}
+
+func runListContexts(args []string) error {
+	flags := newFlagSet("list-contexts")
+	target := addTargetFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	token, owner, repoName, sha, err := target.parse()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	names, err := github.NewService(ctx, token).ListContexts(ctx, owner, repoName, sha)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
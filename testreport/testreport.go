@@ -0,0 +1,200 @@
+package testreport
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Failure is a single failed test case from a test report.
+type Failure struct {
+	Suite string
+	Name  string
+	// Message is the first line of the failure output.
+	Message string
+}
+
+func (f Failure) FullName() string {
+	if f.Suite == "" {
+		return f.Name
+	}
+	return f.Suite + "." + f.Name
+}
+
+// ErrUnsupportedFormat is returned for files that don't look like a supported report format, like a Cobertura
+// coverage.xml or a JSON file that isn't go test output, so that they can be skipped.
+var ErrUnsupportedFormat = errors.New("unsupported test report format")
+
+// Parse reads the failures from a JUnit XML (.xml) or `go test -json` (.json) report, based on the file name.
+func Parse(fileName string, r io.Reader) ([]Failure, error) {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".xml":
+		return ParseJUnit(r)
+	case ".json":
+		return ParseGoTestJSON(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type junitTestSuites struct {
+	Suites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Suites    []junitTestSuite `xml:"testsuite"`
+	TestCases []junitTestCase  `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []junitProblem `xml:"failure"`
+	Errors    []junitProblem `xml:"error"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// ParseJUnit reads the failures from a JUnit XML report. Both <testsuites> and bare <testsuite> roots are supported.
+func ParseJUnit(r io.Reader) ([]Failure, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid junit report - %w", err)
+	}
+
+	var suites []junitTestSuite
+	switch root.XMLName.Local {
+	case "testsuites":
+		var doc junitTestSuites
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid junit report - %w", err)
+		}
+		suites = doc.Suites
+	case "testsuite":
+		var doc junitTestSuite
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid junit report - %w", err)
+		}
+		suites = []junitTestSuite{doc}
+	default:
+		return nil, fmt.Errorf("%w - unexpected root element <%s>", ErrUnsupportedFormat, root.XMLName.Local)
+	}
+
+	var failures []Failure
+	for _, suite := range suites {
+		failures = append(failures, junitSuiteFailures(suite)...)
+	}
+	return failures, nil
+}
+
+func junitSuiteFailures(suite junitTestSuite) []Failure {
+	var failures []Failure
+	for _, testCase := range suite.TestCases {
+		problems := append(testCase.Failures, testCase.Errors...)
+		if len(problems) == 0 {
+			continue
+		}
+
+		suiteName := testCase.ClassName
+		if suiteName == "" {
+			suiteName = suite.Name
+		}
+
+		message := problems[0].Message
+		if message == "" {
+			message = problems[0].Body
+		}
+
+		failures = append(failures, Failure{Suite: suiteName, Name: testCase.Name, Message: firstLine(message)})
+	}
+
+	for _, nested := range suite.Suites {
+		failures = append(failures, junitSuiteFailures(nested)...)
+	}
+	return failures
+}
+
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+// ParseGoTestJSON reads the failed tests from the output of `go test -json`. If the first JSON line isn't a test event,
+// the file is some other kind of JSON and ErrUnsupportedFormat is returned.
+func ParseGoTestJSON(r io.Reader) ([]Failure, error) {
+	type testKey struct{ pkg, test string }
+	firstOutput := make(map[testKey]string)
+
+	var failures []Failure
+	seenEvent := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		var event goTestEvent
+		err := json.Unmarshal([]byte(line), &event)
+		if !seenEvent && (err != nil || event.Action == "") {
+			return nil, fmt.Errorf("%w - the first JSON line isn't a go test event", ErrUnsupportedFormat)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid go test json report - %w", err)
+		}
+		seenEvent = true
+
+		if event.Test == "" {
+			continue
+		}
+
+		key := testKey{event.Package, event.Test}
+		switch event.Action {
+		case "output":
+			output := strings.TrimSpace(event.Output)
+			if _, seen := firstOutput[key]; !seen && output != "" && !isGoTestFraming(output) {
+				firstOutput[key] = output
+			}
+		case "fail":
+			failures = append(failures, Failure{Suite: event.Package, Name: event.Test, Message: firstOutput[key]})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+func isGoTestFraming(output string) bool {
+	for _, prefix := range []string{"=== ", "--- "} {
+		if strings.HasPrefix(output, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
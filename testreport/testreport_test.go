@@ -0,0 +1,148 @@
+package testreport
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJUnit(t *testing.T) {
+	tests := []struct {
+		name, report string
+		want         []Failure
+	}{
+		{
+			name: "testsuites root",
+			report: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="checkout">
+    <testcase name="adds to basket" classname="checkout.Basket"/>
+    <testcase name="pays" classname="checkout.Payment">
+      <failure message="expected 200, got 500">stack trace</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="search">
+    <testcase name="finds products">
+      <error>timed out
+after 30s</error>
+    </testcase>
+  </testsuite>
+</testsuites>`,
+			want: []Failure{
+				{Suite: "checkout.Payment", Name: "pays", Message: "expected 200, got 500"},
+				{Suite: "search", Name: "finds products", Message: "timed out"},
+			},
+		},
+		{
+			name: "testsuite root with nested suites",
+			report: `<testsuite name="outer">
+  <testcase name="passes"/>
+  <testsuite name="inner">
+    <testcase name="fails"><failure message="boom"/></testcase>
+  </testsuite>
+</testsuite>`,
+			want: []Failure{{Suite: "inner", Name: "fails", Message: "boom"}},
+		},
+		{
+			name:   "no failures",
+			report: `<testsuites><testsuite name="all"><testcase name="passes"/></testsuite></testsuites>`,
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJUnit(strings.NewReader(tt.report))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseJUnitErrors(t *testing.T) {
+	for _, report := range []string{"not xml", "<results><testcase/></results>"} {
+		if _, err := ParseJUnit(strings.NewReader(report)); err == nil {
+			t.Errorf("ParseJUnit(%q): expected an error", report)
+		}
+	}
+}
+
+func TestParseGoTestJSON(t *testing.T) {
+	report := `go: downloading example.com/dep v1.0.0
+{"Action":"run","Package":"example.com/shop","Test":"TestCheckout"}
+{"Action":"output","Package":"example.com/shop","Test":"TestCheckout","Output":"=== RUN   TestCheckout\n"}
+{"Action":"output","Package":"example.com/shop","Test":"TestCheckout","Output":"    shop_test.go:12: expected 2 items, got 1\n"}
+{"Action":"output","Package":"example.com/shop","Test":"TestCheckout","Output":"    shop_test.go:13: second problem\n"}
+{"Action":"output","Package":"example.com/shop","Test":"TestCheckout","Output":"--- FAIL: TestCheckout (0.01s)\n"}
+{"Action":"fail","Package":"example.com/shop","Test":"TestCheckout"}
+{"Action":"pass","Package":"example.com/shop","Test":"TestSearch"}
+{"Action":"fail","Package":"example.com/shop","Test":"TestSilent"}
+{"Action":"fail","Package":"example.com/shop"}
+`
+	got, err := ParseGoTestJSON(strings.NewReader(report))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Failure{
+		{Suite: "example.com/shop", Name: "TestCheckout", Message: "shop_test.go:12: expected 2 items, got 1"},
+		{Suite: "example.com/shop", Name: "TestSilent"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestParseGoTestJSONErrors(t *testing.T) {
+	report := `{"Action":"run","Package":"p","Test":"TestT"}
+{"Action":`
+	_, err := ParseGoTestJSON(strings.NewReader(report))
+	if err == nil || errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected a go test report cut short to be an error, got %v", err)
+	}
+}
+
+func TestParseUnsupportedFormats(t *testing.T) {
+	tests := []struct {
+		fileName, content string
+	}{
+		{fileName: "coverage.xml", content: `<?xml version="1.0"?><coverage line-rate="0.8"><packages/></coverage>`},
+		{fileName: "package.json", content: "{\n  \"name\": \"shop\"\n}\n"},
+		{fileName: "summary.json", content: `{"total": 12, "failed": 1}`},
+		{fileName: "notes.txt", content: "not a report"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.fileName, strings.NewReader(tt.content)); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("Parse(%q): expected ErrUnsupportedFormat, got %v", tt.fileName, err)
+		}
+	}
+}
+
+func TestParseChoosesTheFormatFromTheFileName(t *testing.T) {
+	junit := `<testsuite name="s"><testcase name="t"><failure message="m"/></testcase></testsuite>`
+	if got, err := Parse("reports/JUNIT.XML", strings.NewReader(junit)); err != nil || len(got) != 1 {
+		t.Errorf("expected one failure from the junit report, got %+v and %v", got, err)
+	}
+
+	goTest := `{"Action":"fail","Package":"p","Test":"TestT"}`
+	if got, err := Parse("go-test.json", strings.NewReader(goTest)); err != nil || len(got) != 1 {
+		t.Errorf("expected one failure from the go test report, got %+v and %v", got, err)
+	}
+
+	if _, err := Parse("results.txt", strings.NewReader("")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestFullName(t *testing.T) {
+	if got := (Failure{Suite: "checkout", Name: "pays"}).FullName(); got != "checkout.pays" {
+		t.Errorf("expected checkout.pays, got %s", got)
+	}
+	if got := (Failure{Name: "pays"}).FullName(); got != "pays" {
+		t.Errorf("expected pays, got %s", got)
+	}
+}
@@ -0,0 +1,211 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The types below are the parts of the OTLP/HTTP JSON encoding that are needed to export spans.
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+const (
+	spanKindInternal = 1
+	statusCodeOK     = 1
+	statusCodeError  = 2
+)
+
+// Export sends every span to an OTLP/HTTP collector, e.g http://localhost:4318.
+func (t *Tracer) Export(ctx context.Context, endpoint string) error {
+	if t == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(t.otlp())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected status code from otlp endpoint: %d %s", res.StatusCode, strings.TrimSpace(string(resBody)))
+	}
+	return nil
+}
+
+// WriteFile writes every span to a file in the same JSON encoding that Export sends.
+func (t *Tracer) WriteFile(path string) error {
+	if t == nil {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(t.otlp(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func (t *Tracer) otlp() otlpTraces {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]otlpSpan, 0, len(t.spans))
+	for _, span := range t.spans {
+		spans = append(spans, span.otlp())
+	}
+
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: toKeyValues(map[string]any{"service.name": t.serviceName})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: t.serviceName}, Spans: spans}},
+	}}}
+}
+
+func (s *Span) otlp() otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	end := s.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	status := otlpStatus{Code: statusCodeOK}
+	if s.failed {
+		status = otlpStatus{Code: statusCodeError, Message: s.statusMsg}
+	}
+
+	var events []otlpEvent
+	for _, e := range s.events {
+		events = append(events, otlpEvent{TimeUnixNano: unixNano(e.at), Name: e.name, Attributes: toKeyValues(e.attributes)})
+	}
+
+	return otlpSpan{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentSpanID,
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(s.start),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes:        toKeyValues(s.attributes),
+		Events:            events,
+		Status:            status,
+	}
+}
+
+func toKeyValues(attributes map[string]any) []otlpKeyValue {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var kvs []otlpKeyValue
+	for _, key := range keys {
+		kvs = append(kvs, otlpKeyValue{Key: key, Value: toValue(attributes[key])})
+	}
+	return kvs
+}
+
+func toValue(v any) otlpValue {
+	switch v := v.(type) {
+	case string:
+		return otlpValue{StringValue: &v}
+	case int:
+		s := strconv.Itoa(v)
+		return otlpValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return otlpValue{IntValue: &s}
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	case bool:
+		return otlpValue{BoolValue: &v}
+	default:
+		s := fmt.Sprint(v)
+		return otlpValue{StringValue: &s}
+	}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
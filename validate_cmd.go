@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/tamj0rd2/pipeline-status-action/configfile"
+)
+
+// runValidate checks the flags that wait would be given, so that a workflow can be checked before it's relied on. The
+// commit to check isn't needed, because nothing is fetched from GitHub.
+func runValidate(args []string) error {
+	flags := newFlagSet("validate")
+	waitFlags := addWaitFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	repoFile, err := readRepoConfig(orEnv(*waitFlags.repoConfigPath, flagEnvName("repoConfig")))
+	if err != nil {
+		return err
+	}
+
+	config, err := waitFlags.parseSettings(repoFile)
+	if err != nil {
+		return err
+	}
+
+	if _, err := waitFlags.log.setDefaultLogger(nil, config.redactPatterns); err != nil {
+		return err
+	}
+
+	fmt.Println("configuration is valid")
+	return nil
+}
+
+// readRepoConfig reads the repository's config file from the current directory, rather than from the commit being
+// checked like wait does. It's skipped if it isn't there, since it's optional.
+func readRepoConfig(path string) (configfile.File, error) {
+	if path == "" {
+		return configfile.File{}, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("%s doesn't exist here, so it wasn't checked\n", path)
+		return configfile.File{}, nil
+	}
+	if err != nil {
+		return configfile.File{}, usageErrorf("failed to read %s - %w", path, err)
+	}
+
+	file, err := configfile.Parse(bytes.NewReader(content))
+	if err != nil {
+		return configfile.File{}, usageErrorf("%s is invalid - %w", path, err)
+	}
+	return file, nil
+}
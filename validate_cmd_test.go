@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunValidate(t *testing.T) {
+	// validate doesn't need to know which commit would be checked.
+	for _, env := range []string{"GITHUB_TOKEN", "GITHUB_SHA", "GITHUB_REPOSITORY"} {
+		t.Setenv(env, "")
+	}
+
+	dir := t.TempDir()
+	validRepoConfig := filepath.Join(dir, "valid.yml")
+	if err := os.WriteFile(validRepoConfig, []byte("version: 1\nchecks: [build, test]\nstages: [{name: build, checks: [build]}, {name: test, checks: [test]}]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	invalidRepoConfig := filepath.Join(dir, "invalid.yml")
+	if err := os.WriteFile(invalidRepoConfig, []byte("version: 1\nchecks: [build]\nstages: [{name: test, checks: [e2e-*]}]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	envNotifierRepoConfig := filepath.Join(dir, "notifier.yml")
+	if err := os.WriteFile(envNotifierRepoConfig, []byte("version: 1\nchecks: [build]\nnotifiers: {default: {webhookURLEnv: AWS_SECRET_ACCESS_KEY}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		// wantErr is part of the usage error expected, or empty if the config should be valid.
+		wantErr string
+	}{
+		{name: "flags only", args: []string{"-checkNames=build"}},
+		{name: "invalid flags", args: []string{"-checkNames=build", "-mode=eventually"}, wantErr: "mode must be"},
+		{name: "a valid repository config file", args: []string{"-repoConfig=" + validRepoConfig}},
+		{name: "an invalid repository config file", args: []string{"-repoConfig=" + invalidRepoConfig}, wantErr: "stage"},
+		{name: "a repository config file reading the environment", args: []string{"-repoConfig=" + envNotifierRepoConfig}, wantErr: "can't read $AWS_SECRET_ACCESS_KEY"},
+		{name: "a repository config file that isn't here", args: []string{"-checkNames=build", "-repoConfig=" + filepath.Join(dir, "missing.yml")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runValidate(tt.args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var usageErr usageError
+			if !errors.As(err, &usageErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected a usage error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
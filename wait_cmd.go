@@ -0,0 +1,589 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tamj0rd2/pipeline-status-action/actions"
+	"github.com/tamj0rd2/pipeline-status-action/configfile"
+	"github.com/tamj0rd2/pipeline-status-action/github"
+	"github.com/tamj0rd2/pipeline-status-action/history"
+	"github.com/tamj0rd2/pipeline-status-action/metrics"
+	"github.com/tamj0rd2/pipeline-status-action/policy"
+	"github.com/tamj0rd2/pipeline-status-action/redact"
+	"github.com/tamj0rd2/pipeline-status-action/report"
+	"github.com/tamj0rd2/pipeline-status-action/slack"
+	"github.com/tamj0rd2/pipeline-status-action/tracing"
+)
+
+type config struct {
+	token, sha      string
+	owner           string
+	repoName        string
+	statusNames     []string
+	slackWebhookURL string
+	timeout         time.Duration
+	logExcerptLines int
+	redactPatterns  []string
+
+	testReportArtifacts []string
+	maxFailedTests      int
+	maxAnnotations      int
+	retryPolicies       []github.RetryPolicy
+	checkTimeouts       []github.CheckTimeouts
+	mode                string
+	earlyAlerts         bool
+	policy              *policy.Policy
+	severities          []github.CheckSeverity
+	stages              []github.Stage
+	historyFile         string
+	reportFile          string
+	junitReportFile     string
+	metricsFile         string
+	pushgatewayURL      string
+	metricsAddr         string
+	otlpEndpoint        string
+	traceFile           string
+	branch              string
+
+	// notifiers are the webhook URLs of the notifiers named in the config file, other than the default one, which is
+	// slackWebhookURL.
+	notifiers map[string]string
+	routes    []configfile.Route
+	// repoConfigPath is where the config file is looked for in the repository being checked. Empty means it isn't.
+	repoConfigPath string
+	// file is the config file the rest of the config was layered over, merged from the central and repository ones.
+	file configfile.File
+}
+
+// webhookURL returns the webhook URL of a notifier, which is empty if it hasn't got one.
+func (c config) webhookURL(notifier string) string {
+	if notifier == configfile.DefaultNotifier {
+		return c.slackWebhookURL
+	}
+	return c.notifiers[notifier]
+}
+
+// secrets returns everything in the config that mustn't be logged or posted.
+func (c config) secrets() []string {
+	secrets := []string{c.token, c.slackWebhookURL}
+	for _, url := range c.notifiers {
+		secrets = append(secrets, url)
+	}
+	return secrets
+}
+
+// waitFlags are the flags of the wait command. validate shares them so that it checks exactly what wait would use.
+type waitFlags struct {
+	flags  *flag.FlagSet
+	target targetFlags
+	log    logFlags
+
+	configPath, repoConfigPath *string
+
+	checkNames, slackWebhookURL, redactPatterns, testReportArtifacts, retries, checkTimeouts *string
+	historyFile, reportFile, junitReportFile, metricsFile, pushgatewayURL, metricsAddr       *string
+	otlpEndpoint, traceFile, branch, mode, policy, severities, stages                        *string
+	timeoutMinutes, logExcerptLines, maxFailedTests, maxAnnotations                          *int
+	earlyAlerts                                                                              *bool
+}
+
+func addWaitFlags(flags *flag.FlagSet) waitFlags {
+	return waitFlags{
+		flags:  flags,
+		target: addTargetFlags(flags),
+		log:    addLogFlags(flags),
+
+		configPath:     flags.String(configFlag, "", "A YAML config file to read settings from. Defaults to "+configfile.DefaultPath+" if it exists"),
+		repoConfigPath: flags.String("repoConfig", "", "A config file to read from the repository being checked, at the commit being checked, e.g "+configfile.DefaultPath+". Its settings are layered over the other config file"),
+
+		checkNames:          flags.String("checkNames", "", "A comma separated list of the checks to wait for, e.g check1,check2,check3"),
+		slackWebhookURL:     flags.String("slackWebhookURL", "", "The slack webhook URL to send alerts to. Alerts aren't sent if it's empty"),
+		timeoutMinutes:      flags.Int("timeoutMinutes", 60, "The number of minutes to timeout after"),
+		logExcerptLines:     flags.Int("logExcerptLines", 20, "The number of job log lines to include in alerts for failed GitHub Actions jobs. 0 disables it"),
+		redactPatterns:      flags.String("redactPatterns", "", "A newline separated list of regular expressions to redact from anything that gets posted"),
+		testReportArtifacts: flags.String("testReportArtifacts", "", "A comma separated list of workflow artifacts containing JUnit XML or go test -json reports to list failed tests from"),
+		maxFailedTests:      flags.Int("maxFailedTests", 10, "The maximum number of failed tests to list per status"),
+		maxAnnotations:      flags.Int("maxAnnotations", 5, "The maximum number of check run annotations to include per failed check. 0 disables it"),
+		retries:             flags.String("retries", "", "A comma separated list of check name patterns and how many times to re-run their failed jobs, e.g e2e-*=2,flaky=1"),
+		checkTimeouts:       flags.String("checkTimeouts", "", "A comma separated list of check name patterns and their deadlines, e.g lint=appear:2m finish:5m,e2e-*=finish:50m expected:30m"),
+		stages:              flags.String("stages", "", "A semicolon separated list of stages in the order they run, each with a comma separated list of check name patterns, e.g build=build,lint;test=unit-*,e2e-*. Later stages aren't waited for once one fails"),
+		policy:              flags.String("policy", "", "An expression the checks must satisfy instead of all of them succeeding, e.g build AND (e2e-chrome OR e2e-firefox). The checks it names are waited for along with checkNames"),
+		severities:          flags.String("severities", "", "A comma separated list of check name patterns and their severity, required, warn or info, e.g perf-*=info,coverage=warn. Only required checks fail the gate, and failed warn checks get a softer alert"),
+		mode:                flags.String("mode", github.ModeFailFast, "fail-fast to stop at the first failed check, or collect-all to wait for every check to finish and report them all"),
+		earlyAlerts:         flags.Bool("earlyAlerts", false, "In collect-all mode, alert about each check as soon as it's given up on, as well as sending a final summary"),
+		historyFile:         flags.String("historyFile", "", "A file to record runs and check outcomes in, used to tag known flaky checks in alerts"),
+		reportFile:          flags.String("report", "", "A file to write a JSON report of the run to"),
+		junitReportFile:     flags.String("junitReport", "", "A file to write a JUnit XML report of the tracked checks to"),
+		metricsFile:         flags.String("metricsFile", "", "A file to write OpenMetrics metrics to"),
+		pushgatewayURL:      flags.String("pushgatewayURL", "", "The URL of a Prometheus Pushgateway to push metrics to"),
+		metricsAddr:         flags.String("metricsAddr", "", "An address to serve metrics on at /metrics while waiting, e.g :9090"),
+		otlpEndpoint:        flags.String("otlpEndpoint", "", "The base URL of an OTLP/HTTP collector to send a trace of the wait to, e.g http://localhost:4318"),
+		traceFile:           flags.String("traceFile", "", "A file to write a trace of the wait to, in the OTLP JSON encoding"),
+		branch:              flags.String("branch", "", "The branch the commit was pushed to, recorded in the run history. Defaults to $GITHUB_REF_NAME"),
+	}
+}
+
+// parse layers the environment, the repository's config file and the central config file under the flags that were
+// given, then validates the result along with the commit to check. repoFile is empty until the repository's file has
+// been fetched.
+func (f waitFlags) parse(repoFile configfile.File) (config, error) {
+	c, err := f.parseSettings(repoFile)
+	if err != nil {
+		return config{}, err
+	}
+
+	c.token, c.owner, c.repoName, c.sha, err = f.target.parse()
+	if err != nil {
+		return config{}, err
+	}
+	return c, nil
+}
+
+// parseSettings is parse without the commit to check, which validate has no need for.
+func (f waitFlags) parseSettings(repoFile configfile.File) (config, error) {
+	centralFile, _, err := configfile.Load(orEnv(*f.configPath, flagEnvName(configFlag)))
+	if err != nil {
+		return config{}, usageErrorf("failed to load config file - %w", err)
+	}
+	if err := configfile.CheckRepoNotifiers(centralFile, repoFile); err != nil {
+		return config{}, usageErrorf("the repository's config file is invalid - %w", err)
+	}
+	file := configfile.Merge(centralFile, repoFile)
+	if err := file.CheckReferences(); err != nil {
+		return config{}, usageErrorf("the config files are invalid - %w", err)
+	}
+
+	if err := applyConfigLayers(f.flags, file); err != nil {
+		return config{}, err
+	}
+
+	var gatePolicy *policy.Policy
+	if *f.policy != "" {
+		parsed, err := policy.Parse(*f.policy)
+		if err != nil {
+			return config{}, usageErrorf("invalid policy - %w", err)
+		}
+		gatePolicy = &parsed
+	}
+
+	checkNames := splitList(*f.checkNames)
+	if len(checkNames) == 0 && gatePolicy == nil {
+		return config{}, usageErrorf("checkNames or policy is required")
+	}
+
+	if *f.timeoutMinutes <= 0 {
+		return config{}, usageErrorf("timeoutMinutes must be more than 0")
+	}
+
+	if *f.logExcerptLines < 0 {
+		return config{}, usageErrorf("logExcerptLines must not be negative")
+	}
+
+	if *f.maxFailedTests < 0 {
+		return config{}, usageErrorf("maxFailedTests must not be negative")
+	}
+
+	if *f.maxAnnotations < 0 {
+		return config{}, usageErrorf("maxAnnotations must not be negative")
+	}
+
+	retryPolicies, err := parseRetryPolicies(*f.retries)
+	if err != nil {
+		return config{}, err
+	}
+
+	checkTimeouts, err := parseCheckTimeouts(*f.checkTimeouts)
+	if err != nil {
+		return config{}, err
+	}
+
+	severities, err := parseSeverities(*f.severities)
+	if err != nil {
+		return config{}, err
+	}
+	if err := checkSomeRequired(checkNames, severities, gatePolicy); err != nil {
+		return config{}, err
+	}
+
+	stages, err := parseStages(*f.stages)
+	if err != nil {
+		return config{}, err
+	}
+	if err := checkStagesMatch(stages, checkNames, gatePolicy); err != nil {
+		return config{}, err
+	}
+
+	if *f.mode != github.ModeFailFast && *f.mode != github.ModeCollectAll {
+		return config{}, usageErrorf("mode must be %s or %s, got %q", github.ModeFailFast, github.ModeCollectAll, *f.mode)
+	}
+
+	if *f.earlyAlerts && *f.mode != github.ModeCollectAll {
+		return config{}, usageErrorf("earlyAlerts needs mode %s, because fail-fast only ever sends one alert", github.ModeCollectAll)
+	}
+
+	return config{
+		statusNames:     checkNames,
+		slackWebhookURL: *f.slackWebhookURL,
+		timeout:         time.Minute * time.Duration(*f.timeoutMinutes),
+		logExcerptLines: *f.logExcerptLines,
+		redactPatterns:  strings.Split(*f.redactPatterns, "\n"),
+
+		testReportArtifacts: splitList(*f.testReportArtifacts),
+		maxFailedTests:      *f.maxFailedTests,
+		maxAnnotations:      *f.maxAnnotations,
+		retryPolicies:       retryPolicies,
+		checkTimeouts:       checkTimeouts,
+		mode:                *f.mode,
+		earlyAlerts:         *f.earlyAlerts,
+		policy:              gatePolicy,
+		severities:          severities,
+		stages:              stages,
+		historyFile:         *f.historyFile,
+		reportFile:          *f.reportFile,
+		junitReportFile:     *f.junitReportFile,
+		metricsFile:         *f.metricsFile,
+		pushgatewayURL:      *f.pushgatewayURL,
+		metricsAddr:         *f.metricsAddr,
+		otlpEndpoint:        *f.otlpEndpoint,
+		traceFile:           *f.traceFile,
+		branch:              orEnv(*f.branch, "GITHUB_REF_NAME"),
+
+		notifiers:      fileNotifiers(file),
+		routes:         file.Routes,
+		repoConfigPath: *f.repoConfigPath,
+		file:           file,
+	}, nil
+}
+
+// fileNotifiers returns the webhook URLs of the notifiers in the config file. The default notifier is left out,
+// because it's layered under the slackWebhookURL flag.
+func fileNotifiers(file configfile.File) map[string]string {
+	notifiers := make(map[string]string)
+	for _, name := range file.NotifierNames() {
+		if name != configfile.DefaultNotifier {
+			notifiers[name] = file.Notifiers[name].URL()
+		}
+	}
+	return notifiers
+}
+
+func runWait(args []string) error {
+	flags := newFlagSet("wait")
+	waitFlags := addWaitFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	config, err := waitFlags.parse(configfile.File{})
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range config.secrets() {
+		actions.AddMask(secret)
+	}
+
+	redactor, err := waitFlags.log.setDefaultLogger(config.secrets(), config.redactPatterns)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	service := github.NewService(ctx, config.token)
+
+	// the repository's file is checked against the rest of the config too, e.g that its stages match its checks, so
+	// a mistake in it is reported to the repository rather than failing the run.
+	repoConfig := config
+	if loadRepoConfig(ctx, service, config, redactor, func(file configfile.File) (err error) {
+		repoConfig, err = waitFlags.parse(file)
+		return err
+	}) {
+		config = repoConfig
+
+		// the repository's file can add notifiers, whose webhook URLs need hiding too.
+		for _, secret := range config.secrets() {
+			actions.AddMask(secret)
+		}
+		if redactor, err = waitFlags.log.setDefaultLogger(config.secrets(), config.redactPatterns); err != nil {
+			return err
+		}
+	}
+
+	registry := metrics.NewRegistry()
+	registerAPIMetrics(registry, service)
+	if config.metricsAddr != "" {
+		serveMetrics(config.metricsAddr, registry)
+	}
+
+	return waitAndNotify(ctx, service, config, redactor, registry)
+}
+
+// waitAndNotify waits for the checks, records the outcome everywhere it has been asked to, and sends an alert if the
+// checks didn't succeed. The returned error says why they didn't.
+func waitAndNotify(ctx context.Context, service *github.Service, config config, redactor *redact.Redactor, registry *metrics.Registry) error {
+	logger := slog.Default().With("owner", config.owner, "repo", config.repoName, "sha", config.sha)
+
+	var tracer *tracing.Tracer
+	if config.otlpEndpoint != "" || config.traceFile != "" {
+		tracer = tracing.NewTracer("pipeline-status-action")
+	}
+
+	waitOptions := github.WaitOptions{
+		Timeout:       config.timeout,
+		CheckNames:    config.statusNames,
+		RetryPolicies: config.retryPolicies,
+		CheckTimeouts: config.checkTimeouts,
+		Mode:          config.mode,
+		Policy:        config.policy,
+		Severities:    config.severities,
+		Stages:        config.stages,
+		Tracer:        tracer,
+		Logger:        logger,
+	}
+
+	var commit github.CommitInfo
+	if config.earlyAlerts {
+		commit = getCommitInfo(ctx, service, config)
+		waitOptions.OnCheckGivenUp = func(ctx context.Context, status github.Status) {
+			// info checks are only there to be reported, so they're never alerted about.
+			if status.Severity == github.SeverityInfo {
+				return
+			}
+
+			title := ":warning: A commit status failed"
+			if status.Severity == github.SeverityWarn {
+				title = ":warning: A non-blocking commit status failed"
+			}
+			alert := slack.Alert{
+				Title:          title,
+				Commit:         commit,
+				ErrorMessage:   fmt.Sprintf("%s is %s, still waiting for the other checks before sending a summary", status.Name, status.State()),
+				MaxFailedTests: config.maxFailedTests,
+			}
+			notify(ctx, service, config, redactor, logger, []github.Status{status}, alert)
+		}
+	}
+
+	startedAt := time.Now()
+	statuses, err := service.WaitForChecksToSucceed(ctx, config.owner, config.repoName, config.sha, waitOptions)
+	if outputErr := writeActionsOutputs(statuses, github.StageResults(statuses, config.stages), err, time.Since(startedAt)); outputErr != nil {
+		logger.Warn("failed to write step outputs", "error", outputErr)
+	}
+
+	exportTrace(ctx, tracer, config)
+	recordCheckMetrics(registry, config.owner+"/"+config.repoName, statuses, startedAt)
+	exportMetrics(ctx, registry, config)
+
+	warnings := github.NonBlockingFailures(statuses, github.SeverityWarn)
+	if commit.SHA == "" && (err != nil || len(warnings) > 0 || config.reportFile != "") {
+		commit = getCommitInfo(ctx, service, config)
+	}
+
+	if config.junitReportFile != "" {
+		if reportErr := report.WriteJUnit(config.junitReportFile, config.owner+"/"+config.repoName, statuses); reportErr != nil {
+			logger.Warn("failed to write junit report", "error", reportErr)
+		}
+	}
+
+	if config.reportFile != "" {
+		if reportErr := writeReport(config, commit, statuses, err, service.APIStats(), startedAt); reportErr != nil {
+			logger.Warn("failed to write report", "error", reportErr)
+		}
+	}
+
+	var knownFlaky map[string]bool
+	if config.historyFile != "" {
+		store := history.NewStore(config.historyFile)
+		if historyErr := recordOutcomes(store, config, statuses); historyErr != nil {
+			logger.Warn("failed to record check outcomes", "error", historyErr)
+		}
+		if historyErr := recordRun(store, config, statuses, err, startedAt); historyErr != nil {
+			logger.Warn("failed to record run", "error", historyErr)
+		}
+
+		outcomes, historyErr := store.Outcomes()
+		if historyErr != nil {
+			logger.Warn("failed to read check history", "error", historyErr)
+		}
+		knownFlaky = history.KnownFlaky(outcomes, config.owner+"/"+config.repoName)
+	}
+
+	for _, status := range warnings {
+		logger.Warn("non-blocking check did not succeed", "check", status.Name, "state", status.State(), "attempt", status.Retries+1, "missedDeadline", status.MissedDeadline, "url", status.Url)
+		message := "this check doesn't block the gate"
+		if status.Url != "" {
+			message += " - " + status.Url
+		}
+		actions.Warning(fmt.Sprintf("%s %s", status.Name, status.State()), message)
+	}
+
+	if err == nil {
+		if config.policy != nil {
+			logger.Info("the gate policy passed", "policy", config.policy.String())
+		} else {
+			logger.Info("all required status checks completed successfully")
+		}
+
+		if len(warnings) > 0 {
+			alert := slack.Alert{
+				Title:          ":warning: Non-blocking commit statuses failed",
+				Commit:         commit,
+				ErrorMessage:   "these checks failed, but they don't block the gate",
+				MaxFailedTests: config.maxFailedTests,
+				KnownFlaky:     knownFlaky,
+			}
+			notify(ctx, service, config, redactor, logger, warnings, alert)
+		}
+		return nil
+	}
+
+	// without a policy only required checks can fail the gate, so the others are left to the warnings.
+	gating := statuses
+	if config.policy == nil {
+		gating = github.RequiredStatuses(statuses)
+	}
+
+	// collect-all waited to see how every check turned out, so all of the ones that didn't succeed are reported.
+	failedStatuses := github.UnsuccessfulStatuses(gating)
+	if config.mode != github.ModeCollectAll {
+		failedStatuses = github.FailedStatuses(gating)
+		if len(failedStatuses) == 0 {
+			failedStatuses = github.MissedDeadlineStatuses(gating)
+		}
+		if len(failedStatuses) == 0 {
+			failedStatuses = github.IncompleteStatuses(gating)
+		}
+	}
+	for _, status := range failedStatuses {
+		logger.Error("check did not succeed", "check", status.Name, "state", status.State(), "attempt", status.Retries+1, "missedDeadline", status.MissedDeadline, "suggestions", status.Suggestions, "url", status.Url)
+
+		message := err.Error()
+		if status.Url != "" {
+			message += " - " + status.Url
+		}
+		actions.Error(fmt.Sprintf("%s %s", status.Name, status.State()), message)
+	}
+
+	alert := slack.Alert{
+		Commit:         commit,
+		ErrorMessage:   err.Error(),
+		MaxFailedTests: config.maxFailedTests,
+		KnownFlaky:     knownFlaky,
+		Warnings:       warnings,
+		Stages:         github.StageResults(statuses, config.stages),
+	}
+	if config.earlyAlerts {
+		alert.Title = ":clipboard: Commit statuses failed, final summary"
+	}
+	notify(ctx, service, config, redactor, logger, failedStatuses, alert)
+
+	return err
+}
+
+// notify sends an alert about the given statuses to the notifiers they're routed to, with whatever details about
+// them it has been asked to include. Failing to send is only logged, so that the exit code still says why the gate
+// failed.
+func notify(ctx context.Context, service *github.Service, config config, redactor *redact.Redactor, logger *slog.Logger, failedStatuses []github.Status, alert slack.Alert) {
+	// the details are logged as well as alerted about, so they're fetched even if there's nowhere to send an alert.
+	failedStatuses = attachDetails(ctx, service, config, logger, failedStatuses)
+
+	routed := routeAlerts(config, failedStatuses)
+	if len(routed) == 0 {
+		logger.Info("not sending an alert because no notifier has a webhook URL")
+		return
+	}
+
+	for _, notifier := range sortedKeys(routed) {
+		alert.FailedStatuses = routed[notifier]
+		if notifyErr := slack.AlertThatStatusFailed(ctx, config.webhookURL(notifier), redactor, alert); notifyErr != nil {
+			logger.Error("failed to send alert", "notifier", notifier, "error", notifyErr)
+		} else {
+			logger.Info("slack alert sent", "notifier", notifier)
+		}
+	}
+}
+
+// attachDetails fetches the log excerpts, annotations and test failures of the failed checks, and logs the
+// annotations and test failures so that they show up in the job's output.
+func attachDetails(ctx context.Context, service *github.Service, config config, logger *slog.Logger, failedStatuses []github.Status) []github.Status {
+	if config.logExcerptLines > 0 {
+		var logErrs []error
+		failedStatuses, logErrs = service.AttachLogExcerpts(ctx, config.owner, config.repoName, failedStatuses, config.logExcerptLines)
+		for _, logErr := range logErrs {
+			logger.Warn("failed to get log excerpt", "error", logErr)
+		}
+	}
+
+	if config.maxAnnotations > 0 {
+		var annotationErrs []error
+		failedStatuses, annotationErrs = service.AttachAnnotations(ctx, config.owner, config.repoName, config.sha, failedStatuses, config.maxAnnotations)
+		for _, annotationErr := range annotationErrs {
+			logger.Warn("failed to get annotations", "error", annotationErr)
+		}
+
+		for _, status := range failedStatuses {
+			for _, annotation := range status.Annotations {
+				logger.Info(annotation.Message, "check", status.Name, "level", annotation.Level, "url", annotation.URL)
+			}
+		}
+	}
+
+	if len(config.testReportArtifacts) > 0 {
+		var reportErrs []error
+		failedStatuses, reportErrs = service.AttachTestFailures(ctx, config.owner, config.repoName, failedStatuses, config.testReportArtifacts)
+		for _, reportErr := range reportErrs {
+			logger.Warn("failed to get test failures", "error", reportErr)
+		}
+
+		for _, status := range failedStatuses {
+			for _, failure := range status.FailedTests {
+				logger.Info("test failed", "check", status.Name, "test", failure.FullName(), "message", failure.Message)
+			}
+		}
+	}
+
+	return failedStatuses
+}
+
+// routeAlerts decides which notifiers to alert about which failed checks. Each check follows the first route that
+// matches it, or goes to the default notifier if none do. Notifiers without a webhook URL are left out.
+func routeAlerts(config config, failedStatuses []github.Status) map[string][]github.Status {
+	routed := make(map[string][]github.Status)
+	for _, status := range failedStatuses {
+		notifiers := []string{configfile.DefaultNotifier}
+		for _, route := range config.routes {
+			if route.Matches(status.Name) {
+				notifiers = route.Notifiers
+				break
+			}
+		}
+
+		for _, notifier := range notifiers {
+			if config.webhookURL(notifier) == "" {
+				if notifier == configfile.DefaultNotifier {
+					continue
+				}
+				slog.Warn("not alerting a notifier about a check because it has no webhook URL", "notifier", notifier, "check", status.Name)
+				continue
+			}
+			routed[notifier] = append(routed[notifier], status)
+		}
+	}
+	return routed
+}
+
+// sortedKeys returns the keys of a map in order, so that iterating over it gives the same output every time.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}